@@ -0,0 +1,55 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceLevelIndicatorSpec defines the desired state of ServiceLevelIndicator
+type ServiceLevelIndicatorSpec struct {
+	// Type is the query language of the total and error query. It can be
+	// "PromQL" or "LogQL". If the field is not set, "PromQL" is used.
+	// +kubebuilder:validation:Pattern="^(?i)(promql|logql)?$"
+	Type string `json:"type,omitempty"`
+	// TotalQuery and ErrorQuery are the queries of the SLI. Besides the
+	// "${window}" placeholder, the queries can contain a placeholder for each
+	// parameter, e.g. "${host}", which is replaced by the value provided in
+	// the SLOs referencing the ServiceLevelIndicator.
+	TotalQuery string `json:"totalQuery"`
+	ErrorQuery string `json:"errorQuery"`
+	// Parameters is the list of parameters, which can be used in the queries.
+	Parameters []Parameter `json:"parameters,omitempty"`
+}
+
+type Parameter struct {
+	// Name is the name of the parameter, e.g. "host". The name "window" is
+	// reserved for the window placeholder.
+	// +kubebuilder:validation:Pattern="^[a-zA-Z_][a-zA-Z0-9_]*$"
+	Name string `json:"name"`
+	// Default is the default value of the parameter. If the field is not set,
+	// the parameter must be provided by all SLOs referencing the
+	// ServiceLevelIndicator.
+	Default *string `json:"default,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServiceLevelIndicator is the Schema for the servicelevelindicators API.
+type ServiceLevelIndicator struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ServiceLevelIndicatorSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ServiceLevelIndicatorList contains a list of ServiceLevelIndicator.
+type ServiceLevelIndicatorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServiceLevelIndicator `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServiceLevelIndicator{}, &ServiceLevelIndicatorList{})
+}
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -8,6 +9,49 @@ import (
 type ServiceLevelObjectiveSpec struct {
 	// SLOs is a list of slos for the service
 	SLOs []SLO `json:"slos,omitempty"`
+	// Tenant is the tenant for the generated rules. It is used as
+	// "X-Scope-OrgID" header, when the rules are pushed to the ruler API of
+	// Grafana Mimir and as tenant of the rule groups in a VMRule. If the field
+	// is not set, the tenant is taken from the namespace label configured via
+	// the "SLO_OPERATOR_TENANT_LABEL" environment variable.
+	Tenant string `json:"tenant,omitempty"`
+	// Alertmanager can be used to generate an AlertmanagerConfig for the
+	// Prometheus Operator, which routes the alerts of the SLOs to a receiver
+	// and inhibits redundant alerts.
+	Alertmanager *Alertmanager `json:"alertmanager,omitempty"`
+	// MaintenanceWindows is a list of planned maintenance windows. During a
+	// maintenance window the events of the SLOs are not counted for the
+	// availability and error budget and the "SLOErrorBudgetBurn" and
+	// "SLOMetricAbsent" alerts are suppressed. The "SLOErrorBudgetBurn" alerts
+	// are suppressed for 6 more hours after the end of a maintenance window,
+	// because the burn rates of the short windows still contain the errors of
+	// the maintenance window. For SLOs with a LogQL SLI maintenance windows
+	// are only supported in the "silences" maintenance mode.
+	//
+	// When the "SLO_OPERATOR_MAINTENANCE_MODE" environment variable is set to
+	// "silences", the rules are not changed. Instead the operator creates an
+	// Alertmanager silence for all alerts of the SLOs for each maintenance
+	// window.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// Name is the name of the maintenance window.
+	Name string `json:"name,omitempty"`
+	// Start and End can be used to define a one-off maintenance window.
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+	// Schedule can be used to define a recurring maintenance window as cron
+	// schedule, e.g. "0 2 * * 0" for every Sunday at 2am. The schedule uses the
+	// standard cron format with five fields and also supports names like "SUN"
+	// and descriptors like "@daily". The schedule must be used together with
+	// the duration.
+	Schedule string `json:"schedule,omitempty"`
+	// Duration is the duration of a recurring maintenance window, e.g. "2h".
+	Duration string `json:"duration,omitempty"`
+	// Timezone is the timezone of the schedule, e.g. "Europe/Berlin". If the
+	// field is not set, "UTC" is used.
+	Timezone string `json:"timezone,omitempty"`
 }
 
 type SLO struct {
@@ -17,19 +61,138 @@ type SLO struct {
 	// requests in 200ms", etc. It must be a percentage value between 1 and 100
 	// as string, e.g. "99.9".
 	Objective string `json:"objective,omitempty"`
+	// Objectives can be used to define additional named objectives for the
+	// SLO, e.g. an internal objective which is stricter than the external
+	// one. Each objective has its own alerting configuration, while the
+	// recording rules of the SLI are shared with the objective above. The
+	// metrics and alerts of an additional objective contain an "objective"
+	// label with the name of the objective.
+	Objectives []Objective `json:"objectives,omitempty"`
 	// A description for the SLO.
 	Description string `json:"description,omitempty"`
 	// SLI contains the metrics to calculate the SLO. For example the total
 	// metric is the number of all requests, while the error metric is only the
 	// number of all 5xx requests.
 	SLI SLI `json:"sli,omitempty"`
+	// Composite can be used instead of the SLI to create a composite SLO,
+	// which is calculated from the availability and burn rates of other SLOs,
+	// e.g. for a user journey spanning multiple services.
+	Composite *Composite `json:"composite,omitempty"`
+	// Vars is a map of variables, which can be used in the queries of the SLI
+	// and the absent alert via "${name}", e.g. {"service": "api"}. Besides
+	// the user provided variables, the labels of the ServiceLevelObjective
+	// (including "name" and "namespace") and the name of the SLO ("slo") can
+	// be used. The name "window" is reserved for the window placeholder.
+	Vars map[string]string `json:"vars,omitempty"`
+	// GroupBy is a list of labels, e.g. ["route"], which are kept in all
+	// recorded metrics and alerts of the SLO, so that the SLO is calculated
+	// and alerted for each value of the labels. The total and error query
+	// must aggregate by all labels, e.g. "sum by (route) (...)".
+	GroupBy []string `json:"groupBy,omitempty"`
+	// Window can be used to adjust the window of the SLO, which is used to
+	// calculate the availability and error budget. If the field is not set, a
+	// rolling window of 28 days is used.
+	Window Window `json:"window,omitempty"`
 	// Alerting can be used to adjust the alerting configuration for the SLO.
 	Alerting Alerting `json:"alerting,omitempty"`
+	// RuleGroup can be used to adjust the evaluation options of the rule
+	// groups generated for the SLO.
+	RuleGroup RuleGroup `json:"ruleGroup,omitempty"`
+}
+
+type Objective struct {
+	// Name is the name of the objective, e.g. "internal". The name is added
+	// as "objective" label to all metrics and alerts of the objective.
+	Name string `json:"name"`
+	// Objective is the objective as percentage value between 1 and 100 as
+	// string, e.g. "99.95".
+	Objective string `json:"objective"`
+	// Alerting can be used to adjust the alerting configuration for the
+	// objective.
+	Alerting Alerting `json:"alerting,omitempty"`
 }
 
 type SLI struct {
+	// Type is the query language of the total and error query. It can be
+	// "PromQL" or "LogQL". If the field is not set, "PromQL" is used. The rules
+	// for SLIs with the "LogQL" type are pushed to the ruler API of Grafana
+	// Loki, except for the rules which are only using the recorded metrics.
+	// +kubebuilder:validation:Pattern="^(?i)(promql|logql)?$"
+	Type       string `json:"type,omitempty"`
 	TotalQuery string `json:"totalQuery,omitempty"`
 	ErrorQuery string `json:"errorQuery,omitempty"`
+	// Ref can be used to reference a ServiceLevelIndicator instead of defining
+	// the type and queries inline. The queries of the ServiceLevelIndicator
+	// are rendered with the provided parameters.
+	Ref *ServiceLevelIndicatorRef `json:"ref,omitempty"`
+	// Preset can be used to select a built-in SLI for a common exporter
+	// instead of defining the type and queries inline.
+	Preset *SLIPreset `json:"preset,omitempty"`
+}
+
+type SLIPreset struct {
+	// Name is the name of the preset. It can be "ingress-nginx", "istio",
+	// "envoy", "grpc", "traefik" or "kube-state-metrics".
+	// +kubebuilder:validation:Pattern="^(?i)(ingress-nginx|istio|envoy|grpc|traefik|kube-state-metrics)$"
+	Name string `json:"name"`
+	// Parameters are the values for the parameters of the preset, e.g.
+	// {"host": "example.com"} for the "ingress-nginx" preset.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+type ServiceLevelIndicatorRef struct {
+	// Name is the name of the ServiceLevelIndicator.
+	Name string `json:"name"`
+	// Namespace is the namespace of the ServiceLevelIndicator. If the field
+	// is not set, the namespace of the ServiceLevelObjective is used.
+	Namespace string `json:"namespace,omitempty"`
+	// Parameters are the values for the parameters of the
+	// ServiceLevelIndicator, e.g. {"host": "example.com"}.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+type Composite struct {
+	// Type is the type of the composite SLO. It can be "WeightedAverage" or
+	// "Product". If the field is not set, "WeightedAverage" is used. For
+	// "WeightedAverage" the availability is the weighted average of the
+	// availabilities of the SLOs. For "Product" the availability is the
+	// product of the availabilities of the SLOs, which should be used for
+	// serial dependencies.
+	// +kubebuilder:validation:Pattern="^(?i)(weightedaverage|product)?$"
+	Type string `json:"type,omitempty"`
+	// SLOs is the list of SLOs, which are combined in the composite SLO.
+	SLOs []CompositeSLO `json:"slos"`
+}
+
+type CompositeSLO struct {
+	// Name is the name of the SLO.
+	Name string `json:"name"`
+	// ServiceLevelObjective is the name of the ServiceLevelObjective, which
+	// contains the SLO. If the field is not set, the ServiceLevelObjective of
+	// the composite SLO is used.
+	ServiceLevelObjective string `json:"serviceLevelObjective,omitempty"`
+	// Namespace is the namespace of the ServiceLevelObjective. If the field is
+	// not set, the namespace of the composite SLO is used.
+	Namespace string `json:"namespace,omitempty"`
+	// Weight is the weight of the SLO for the "WeightedAverage" type as
+	// string, e.g. "2". If the field is not set, a weight of "1" is used.
+	Weight string `json:"weight,omitempty"`
+}
+
+type Window struct {
+	// Type is the type of the window. It can be "Rolling" or "Calendar". If
+	// the field is not set, "Rolling" is used. A calendar window is aligned to
+	// the configured period and resets at the start of each period.
+	// +kubebuilder:validation:Pattern="^(?i)(rolling|calendar)?$"
+	Type string `json:"type,omitempty"`
+	// Period is the period of a calendar window. It can be "Week", "Month" or
+	// "Quarter". If the field is not set, "Month" is used. A week starts on
+	// Monday.
+	// +kubebuilder:validation:Pattern="^(?i)(week|month|quarter)?$"
+	Period string `json:"period,omitempty"`
+	// Timezone is the timezone of a calendar window, e.g. "Europe/Berlin". If
+	// the field is not set, "UTC" is used.
+	Timezone string `json:"timezone,omitempty"`
 }
 
 type Alerting struct {
@@ -44,12 +207,190 @@ type Alerting struct {
 	// The default list which is used, when the field is not set is ["critial",
 	// "error", "error", "warning", "warning"]
 	Severities []string `json:"severities,omitempty"`
+	// ErrorBudgetThresholds is a list of thresholds for the remaining error
+	// budget. For each threshold an alert is created, which fires when the
+	// remaining error budget is below the threshold. The alert is named
+	// "SLOErrorBudgetExhausted" for a threshold of "0" and "SLOErrorBudgetLow"
+	// for all other thresholds.
+	ErrorBudgetThresholds []ErrorBudgetThreshold `json:"errorBudgetThresholds,omitempty"`
+	// Forecast can be used to create the "SLOErrorBudgetForecast" alert, which
+	// fires when the error budget is forecasted to be exhausted within the
+	// configured horizon, based on the linear trend of the last day.
+	Forecast *Forecast `json:"forecast,omitempty"`
+	// MinimumEvents is the minimum number of events in the short window of a
+	// burn rate alert, which are required before the alert can fire. This
+	// avoids alerts for services with a low traffic, where a single failed
+	// request results in a high burn rate. The number of events assumes that
+	// the total query returns the number of events per second, e.g. via
+	// "rate".
+	// +kubebuilder:validation:Minimum=0
+	MinimumEvents int `json:"minimumEvents,omitempty"`
+	// TrafficScaledThresholds can be set to "true" to scale the thresholds of
+	// the burn rate alerts with the traffic of the service. The threshold of a
+	// window is raised to the error ratio of a single failed event in the
+	// window, so that a single failed event never fires an alert.
+	TrafficScaledThresholds bool `json:"trafficScaledThresholds,omitempty"`
+	// BurnRateType is the type of the thresholds for the burn rate alerts. It
+	// can be "Static" or "Dynamic". If the field is not set, "Static" is used.
+	// For "Dynamic" burn rates the threshold of each alert is scaled by the
+	// ratio of the average traffic over the SLO window to the traffic in the
+	// long window of the alert, so that the thresholds are higher when the
+	// traffic is lower than usual.
+	// +kubebuilder:validation:Pattern="^(?i)(static|dynamic)?$"
+	BurnRateType string `json:"burnRateType,omitempty"`
+	// Absent can be used to adjust the "SLOMetricAbsent" alert, which fires
+	// when the metrics of the SLI are absent.
+	Absent *AbsentAlerting `json:"absent,omitempty"`
+}
+
+type AbsentAlerting struct {
+	// Disabled can be used to disable the "SLOMetricAbsent" alert, without
+	// disabling the other alerts of the SLO.
+	Disabled bool `json:"disabled,omitempty"`
+	// Query is the query, which is checked via the "absent" function. If the
+	// field is not set, the total query of the SLI is used. The query can
+	// contain the "${window}" placeholder.
+	Query string `json:"query,omitempty"`
+	// Selectors is a list of metric selectors, e.g.
+	// 'http_requests_total{job="api"}'. If the field is set, each selector is
+	// checked via the "absent" function instead of the query, so that the
+	// alert fires when one of the metrics is absent.
+	Selectors []string `json:"selectors,omitempty"`
+	// ErrorQuery can be set to "true" to also check the error query of the SLI
+	// via the "absent" function.
+	ErrorQuery bool `json:"errorQuery,omitempty"`
+	// For is the duration for which the metrics must be absent, before the
+	// alert fires. If the field is not set, "10m" is used.
+	For string `json:"for,omitempty"`
+	// Severity is the severity of the alert. If the field is not set, the
+	// first severity of the "severities" field is used.
+	Severity string `json:"severity,omitempty"`
+}
+
+type Forecast struct {
+	// Horizon is the time range for the forecast, e.g. "7d". If the field is
+	// not set, a horizon of "7d" is used.
+	Horizon string `json:"horizon,omitempty"`
+	// Severity is the severity of the alert. If the field is not set, the
+	// "warning" severity is used.
+	Severity string `json:"severity,omitempty"`
+}
+
+type ErrorBudgetThreshold struct {
+	// Remaining is the remaining error budget in percent, e.g. "50". It must be
+	// a value between 0 and 100 as string.
+	Remaining string `json:"remaining"`
+	// Severity is the severity of the alert.
+	Severity string `json:"severity"`
+}
+
+type Alertmanager struct {
+	// Receiver is the receiver to which the alerts of the SLOs are routed. It
+	// must be a valid receiver of an AlertmanagerConfig, see
+	// https://prometheus-operator.dev/docs/api-reference/api/#monitoring.coreos.com/v1alpha1.Receiver
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Receiver monitoringv1alpha1.Receiver `json:"receiver"`
+}
+
+type RuleGroup struct {
+	// Interval is the evaluation interval of the rule groups. If the field is
+	// not set, the default interval of "30s" is used.
+	Interval string `json:"interval,omitempty"`
+	// QueryOffset is the offset of the rule evaluation timestamp into the past.
+	// When the VMRule is generated, the offset is used as "eval_delay".
+	QueryOffset string `json:"queryOffset,omitempty"`
+	// PartialResponseStrategy is the partial response strategy used by Thanos
+	// Ruler, it can be "abort" or "warn". The field is ignored by Prometheus and
+	// when the VMRule is generated.
+	// +kubebuilder:validation:Pattern="^(?i)(abort|warn)?$"
+	PartialResponseStrategy string `json:"partialResponseStrategy,omitempty"`
+	// Labels are added to all rules of the rule groups.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Limit is the number of alerts an alerting rule and series a recording
+	// rule can produce.
+	Limit int `json:"limit,omitempty"`
+	// VictoriaMetrics contains evaluation options, which are specific for
+	// vmalert. The options are only used when a VMRule is generated.
+	VictoriaMetrics VictoriaMetricsRuleGroup `json:"victoriaMetrics,omitempty"`
+}
+
+type VictoriaMetricsRuleGroup struct {
+	// EvalOffset is the offset in the range of [0...interval] at which the
+	// rule groups are evaluated.
+	EvalOffset string `json:"evalOffset,omitempty"`
+	// Concurrency defines how many rules of a rule group are executed at once.
+	Concurrency int `json:"concurrency,omitempty"`
+	// Params are additional HTTP URL parameters, which are added to each rule
+	// request.
+	Params map[string][]string `json:"params,omitempty"`
+	// Headers are additional HTTP headers, which are added to each rule
+	// request. The headers must be in the form "header-name: value".
+	Headers []string `json:"headers,omitempty"`
 }
 
 // ServiceLevelObjectiveStatus defines the observed state of
 // ServiceLevelObjective.
 type ServiceLevelObjectiveStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Ruler contains the rule groups, which were created by the operator via
+	// the ruler API of Grafana Mimir.
+	Ruler *RulerStatus `json:"ruler,omitempty"`
+	// LokiRuler contains the rule groups for the SLOs with a LogQL SLI, which
+	// were created by the operator via the ruler API of Grafana Loki.
+	LokiRuler *RulerStatus `json:"lokiRuler,omitempty"`
+	// SLOs contains the live status of the SLOs, which is queried from
+	// Prometheus, when the "SLO_OPERATOR_PROMETHEUS_URL" environment variable
+	// is set, and the current period of SLOs with a calendar window.
+	SLOs []SLOStatus `json:"slos,omitempty"`
+	// Silences contains the Alertmanager silences, which were created by the
+	// operator for the maintenance windows, when the
+	// "SLO_OPERATOR_MAINTENANCE_MODE" environment variable is set to
+	// "silences".
+	Silences []SilenceStatus `json:"silences,omitempty"`
+}
+
+// SilenceStatus contains the id and state of an Alertmanager silence for an
+// occurrence of a maintenance window.
+type SilenceStatus struct {
+	// ID is the id of the silence in Alertmanager.
+	ID string `json:"id"`
+	// MaintenanceWindow is the name of the maintenance window.
+	MaintenanceWindow string      `json:"maintenanceWindow,omitempty"`
+	StartsAt          metav1.Time `json:"startsAt"`
+	EndsAt            metav1.Time `json:"endsAt"`
+	// State is the state of the silence, it can be "pending" or "active".
+	State string `json:"state,omitempty"`
+}
+
+// SLOStatus contains the live status of a single SLO.
+type SLOStatus struct {
+	// Name is the name of the SLO.
+	Name string `json:"name"`
+	// Availability is the current value of the "slo:availability" metric.
+	Availability string `json:"availability,omitempty"`
+	// ErrorBudgetRemaining is the current value of the
+	// "slo:error_budget_remaining" metric.
+	ErrorBudgetRemaining string `json:"errorBudgetRemaining,omitempty"`
+	// ErrorBudgetExhaustionTime is the projected time at which the error budget
+	// is exhausted, based on the linear trend of the last day. The field is not
+	// set, when the error budget is not decreasing.
+	ErrorBudgetExhaustionTime *metav1.Time `json:"errorBudgetExhaustionTime,omitempty"`
+	// PeriodStart is the start of the current period of a SLO with a calendar
+	// window.
+	PeriodStart *metav1.Time `json:"periodStart,omitempty"`
+	// PeriodEnd is the end of the current period of a SLO with a calendar
+	// window. The rules of the SLO are regenerated at the end of the period.
+	PeriodEnd *metav1.Time `json:"periodEnd,omitempty"`
+}
+
+// RulerStatus contains the tenant, namespace and names of the rule groups,
+// which are owned by a ServiceLevelObjective in a ruler API.
+type RulerStatus struct {
+	Tenant    string   `json:"tenant,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,13 @@
+// Package assets contains the static assets of the SLO Operator, which are
+// embedded into the operator binary.
+package assets
+
+import (
+	_ "embed"
+)
+
+// ServiceLevelObjectiveDashboard is the Grafana dashboard for a single SLO. It
+// is used as template for the dashboards generated by the operator.
+//
+//go:embed dashboards/servicelevelobjective.json
+var ServiceLevelObjectiveDashboard []byte
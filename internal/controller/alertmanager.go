@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileAlertmanagerConfig creates / updates the AlertmanagerConfig for a
+// ServiceLevelObjective resource, when the "alertmanager" field is set in the
+// spec. If the field is not set, an existing AlertmanagerConfig, which is
+// owned by the ServiceLevelObjective, is deleted.
+func (r *ServiceLevelObjectiveReconciler) reconcileAlertmanagerConfig(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) error {
+	reqLogger := log.FromContext(ctx)
+
+	found := &monitoringv1alpha1.AlertmanagerConfig{}
+	err := r.Get(ctx, types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if slo.Spec.Alertmanager == nil {
+		if exists && metav1.IsControlledBy(found, slo) {
+			reqLogger.Info("Deleting AlertmanagerConfig.")
+			return r.Delete(ctx, found)
+		}
+		return nil
+	}
+
+	alertmanagerConfig := &monitoringv1alpha1.AlertmanagerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      slo.Name,
+			Namespace: slo.Namespace,
+		},
+		Spec: generateAlertmanagerConfigSpec(slo.Name, slo.Spec.Alertmanager.Receiver, slo.Spec.SLOs),
+	}
+
+	err = ctrl.SetControllerReference(slo, alertmanagerConfig, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		reqLogger.Info("Creating a new AlertmanagerConfig.")
+		err = r.Create(ctx, alertmanagerConfig)
+		if err != nil {
+			reqLogger.Error(err, "Failed to create AlertmanagerConfig.")
+			return err
+		}
+
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(found.Spec, alertmanagerConfig.Spec) {
+		return nil
+	}
+
+	reqLogger.Info("Updating an existing AlertmanagerConfig.")
+	alertmanagerConfig.ResourceVersion = found.ResourceVersion
+
+	err = r.Update(ctx, alertmanagerConfig)
+	if err != nil {
+		reqLogger.Error(err, "Failed to update AlertmanagerConfig.")
+		return err
+	}
+
+	return nil
+}
+
+// generateAlertmanagerConfigSpec generates the spec of the AlertmanagerConfig
+// for a ServiceLevelObjective with the provided name and SLOs.
+//
+// The AlertmanagerConfig contains a route, which routes all alerts of the SLOs
+// to the provided receiver, grouped by the alert name and the id of the SLO.
+// It also contains the following inhibit rules, so that only the most relevant
+// alert for a SLO is sent:
+//   - The "SLOMetricAbsent" alert inhibits all "SLOErrorBudgetBurn" alerts,
+//     because the burn rates can not be calculated without the metric.
+//   - The "SLOErrorBudgetBurn" alerts for the fast burn rates (5m and 30m
+//     window) inhibit the alerts for the slow burn rates (2h and 6h window).
+//   - The "SLOErrorBudgetExhausted" alert inhibits the "SLOErrorBudgetLow"
+//     alerts.
+//
+// The burn rate and error budget alerts of grouped SLOs contain the "groupBy"
+// labels, so that these labels must also be equal for the last two inhibit
+// rules. Otherwise an alert for one group would inhibit the alerts for all
+// other groups. The labels of all SLOs are used, which is fine for SLOs without
+// these labels, because Alertmanager treats missing labels as equal. The
+// "SLOMetricAbsent" alert never contains the "groupBy" labels, so that it still
+// inhibits the alerts of all groups.
+//
+// Note: The Prometheus Operator adds a matcher for the namespace of the
+// AlertmanagerConfig to the route and the inhibit rules.
+func generateAlertmanagerConfigSpec(name string, receiver monitoringv1alpha1.Receiver, slos []ricobergerdev1alpha1.SLO) monitoringv1alpha1.AlertmanagerConfigSpec {
+	var groupBy []string
+	for _, slo := range slos {
+		groupBy = append(groupBy, slo.GroupBy...)
+	}
+	slices.Sort(groupBy)
+	groupBy = slices.Compact(groupBy)
+
+	return monitoringv1alpha1.AlertmanagerConfigSpec{
+		Route: &monitoringv1alpha1.Route{
+			Receiver: receiver.Name,
+			GroupBy:  []string{"alertname", "id", "objective"},
+			Matchers: []monitoringv1alpha1.Matcher{
+				{Name: "alertname", Value: "SLOMetricAbsent|SLOErrorBudgetBurn|SLOErrorBudgetLow|SLOErrorBudgetExhausted|SLOErrorBudgetForecast", MatchType: monitoringv1alpha1.MatchRegexp},
+				{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+			},
+		},
+		Receivers: []monitoringv1alpha1.Receiver{receiver},
+		InhibitRules: []monitoringv1alpha1.InhibitRule{
+			{
+				SourceMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOMetricAbsent", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+				},
+				TargetMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOErrorBudgetBurn", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+				},
+				Equal: []string{"id"},
+			},
+			{
+				SourceMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOErrorBudgetBurn", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "window", Value: "5m|30m", MatchType: monitoringv1alpha1.MatchRegexp},
+				},
+				TargetMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOErrorBudgetBurn", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "window", Value: "2h|6h", MatchType: monitoringv1alpha1.MatchRegexp},
+				},
+				Equal: append([]string{"id", "objective"}, groupBy...),
+			},
+			{
+				SourceMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOErrorBudgetExhausted", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+				},
+				TargetMatch: []monitoringv1alpha1.Matcher{
+					{Name: "alertname", Value: "SLOErrorBudgetLow", MatchType: monitoringv1alpha1.MatchEqual},
+					{Name: "name", Value: name, MatchType: monitoringv1alpha1.MatchEqual},
+				},
+				Equal: append([]string{"id", "objective"}, groupBy...),
+			},
+		},
+	}
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	monitoringv1alpha1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ServiceLevelObjective Controller (AlertmanagerConfig)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-alertmanager",
+			Namespace: "default",
+		}
+
+		receiver := monitoringv1alpha1.Receiver{
+			Name: "team-a",
+			WebhookConfigs: []monitoringv1alpha1.WebhookConfig{
+				{URL: ptr.To("http://alertmanager-webhook:8080")},
+			},
+		}
+
+		BeforeEach(func() {
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-alertmanager",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					Alertmanager: &ricobergerdev1alpha1.Alertmanager{
+						Receiver: receiver,
+					},
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			})
+		})
+
+		reconcileResource := func() error {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			return err
+		}
+
+		It("Should create and delete the AlertmanagerConfig", func() {
+			By("Reconciling the created resource")
+			Expect(reconcileResource()).To(Succeed())
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			alertmanagerConfig := &monitoringv1alpha1.AlertmanagerConfig{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, alertmanagerConfig)).To(Succeed())
+			Expect(alertmanagerConfig.OwnerReferences).To(HaveLen(1))
+			Expect(alertmanagerConfig.Spec).To(Equal(generateAlertmanagerConfigSpec("test-alertmanager", receiver, resource.Spec.SLOs)))
+			Expect(alertmanagerConfig.Spec.Route.Receiver).To(Equal("team-a"))
+			Expect(alertmanagerConfig.Spec.Receivers).To(Equal([]monitoringv1alpha1.Receiver{receiver}))
+			Expect(alertmanagerConfig.Spec.InhibitRules).To(HaveLen(3))
+
+			By("Removing the Alertmanager configuration")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Alertmanager = nil
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			err := k8sClient.Get(ctx, typeNamespacedName, alertmanagerConfig)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("generateAlertmanagerConfigSpec", func() {
+	It("Should inhibit the burn rate alerts", func() {
+		spec := generateAlertmanagerConfigSpec("grafana", monitoringv1alpha1.Receiver{Name: "team-a"}, []ricobergerdev1alpha1.SLO{{Name: "availability"}})
+
+		Expect(spec.InhibitRules[0].SourceMatch).To(ContainElement(monitoringv1alpha1.Matcher{Name: "alertname", Value: "SLOMetricAbsent", MatchType: monitoringv1alpha1.MatchEqual}))
+		Expect(spec.InhibitRules[0].TargetMatch).To(ContainElement(monitoringv1alpha1.Matcher{Name: "alertname", Value: "SLOErrorBudgetBurn", MatchType: monitoringv1alpha1.MatchEqual}))
+		Expect(spec.InhibitRules[0].Equal).To(Equal([]string{"id"}))
+
+		Expect(spec.InhibitRules[1].SourceMatch).To(ContainElement(monitoringv1alpha1.Matcher{Name: "window", Value: "5m|30m", MatchType: monitoringv1alpha1.MatchRegexp}))
+		Expect(spec.InhibitRules[1].TargetMatch).To(ContainElement(monitoringv1alpha1.Matcher{Name: "window", Value: "2h|6h", MatchType: monitoringv1alpha1.MatchRegexp}))
+		Expect(spec.InhibitRules[1].Equal).To(Equal([]string{"id", "objective"}))
+
+		for _, matcher := range spec.Route.Matchers {
+			Expect(matcher.Validate()).To(Succeed())
+		}
+	})
+
+	It("Should only inhibit the alerts of the same group", func() {
+		spec := generateAlertmanagerConfigSpec("grafana", monitoringv1alpha1.Receiver{Name: "team-a"}, []ricobergerdev1alpha1.SLO{
+			{Name: "availability", GroupBy: []string{"route"}},
+			{Name: "latency", GroupBy: []string{"method", "route"}},
+			{Name: "errors"},
+		})
+
+		Expect(spec.InhibitRules[0].Equal).To(Equal([]string{"id"}))
+		Expect(spec.InhibitRules[1].Equal).To(Equal([]string{"id", "objective", "method", "route"}))
+		Expect(spec.InhibitRules[2].Equal).To(Equal([]string{"id", "objective", "method", "route"}))
+	})
+})
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// timeNow returns the current time. It is a variable, so that it can be
+// replaced in the tests.
+var timeNow = time.Now
+
+// sloWindow is the window of a SLO, which is used to calculate the
+// availability and error budget of the SLO.
+type sloWindow struct {
+	// Seconds is the length of the window in seconds.
+	Seconds int64
+	// Range is the range of the window, which can be used in a range selector,
+	// e.g. "28d".
+	Range string
+	// Modifier is the modifier, which must be appended to a range selector for
+	// the window. For calendar windows the "@" modifier is used, so that the
+	// range selector always ends at the end of the current period and only
+	// contains the samples of the current period.
+	Modifier string
+	// Start and End are the start and end of the current period. They are only
+	// set for calendar windows.
+	Start time.Time
+	End   time.Time
+}
+
+// IsCalendar returns true, when the window is a calendar window.
+func (w sloWindow) IsCalendar() bool {
+	return !w.End.IsZero()
+}
+
+// generateSLOWindow returns the window for the provided window configuration
+// of a SLO. If the type of the window is not "Calendar", the rolling window of
+// 28 days is returned.
+//
+// For a calendar window the current period is calculated in the configured
+// timezone. Since the period changes over time, the operator must regenerate
+// the rules at the end of each period.
+func generateSLOWindow(window ricobergerdev1alpha1.Window) (sloWindow, error) {
+	if !strings.EqualFold(window.Type, "calendar") {
+		return sloWindow{Seconds: 2419200, Range: "28d"}, nil
+	}
+
+	location, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return sloWindow{}, fmt.Errorf("failed to load timezone: %w", err)
+	}
+
+	start, end, err := calendarPeriod(window.Period, timeNow().In(location))
+	if err != nil {
+		return sloWindow{}, err
+	}
+
+	seconds := int64(end.Sub(start).Seconds())
+
+	return sloWindow{
+		Seconds:  seconds,
+		Range:    fmt.Sprintf("%ds", seconds),
+		Modifier: fmt.Sprintf(" @ %d", end.Unix()),
+		Start:    start,
+		End:      end,
+	}, nil
+}
+
+// calendarPeriod returns the start and end of the period, which contains the
+// provided time. A week starts on Monday. If no period is provided, a month is
+// used.
+func calendarPeriod(period string, now time.Time) (time.Time, time.Time, error) {
+	year, month, day := now.Date()
+
+	switch strings.ToLower(period) {
+	case "week":
+		start := time.Date(year, month, day-(int(now.Weekday())+6)%7, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 0, 7), nil
+	case "", "month":
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	case "quarter":
+		start := time.Date(year, ((month-1)/3)*3+1, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 3, 0), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid calendar period %s", period)
+	}
+}
+
+// generateSLOPeriodStatuses returns a status entry for each of the provided
+// SLOs, which contains the current period for SLOs with a calendar window. It
+// also returns the duration until the earliest period ends, so that the rules
+// can be regenerated for the next period. If no SLO has a calendar window, the
+// returned duration is 0.
+func generateSLOPeriodStatuses(slos []ricobergerdev1alpha1.SLO) ([]ricobergerdev1alpha1.SLOStatus, time.Duration) {
+	var requeueAfter time.Duration
+
+	statuses := make([]ricobergerdev1alpha1.SLOStatus, 0, len(slos))
+	for _, slo := range slos {
+		status := ricobergerdev1alpha1.SLOStatus{Name: slo.Name}
+
+		// An invalid window results in an error during the generation of the
+		// rules, so that it can be ignored here.
+		window, err := generateSLOWindow(slo.Window)
+		if err == nil && window.IsCalendar() {
+			status.PeriodStart = &metav1.Time{Time: window.Start}
+			status.PeriodEnd = &metav1.Time{Time: window.End}
+
+			// We add one second to the end of the period, to ensure that the
+			// rules are generated for the next period.
+			if until := window.End.Sub(timeNow()) + time.Second; requeueAfter == 0 || until < requeueAfter {
+				requeueAfter = until
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, requeueAfter
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	"github.com/VictoriaMetrics/metricsql"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("calendarPeriod", func() {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	It("Should return the current week", func() {
+		start, end, err := calendarPeriod("Week", time.Date(2025, 10, 29, 12, 0, 0, 0, berlin))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(start).To(Equal(time.Date(2025, 10, 27, 0, 0, 0, 0, berlin)))
+		Expect(end).To(Equal(time.Date(2025, 11, 3, 0, 0, 0, 0, berlin)))
+
+		By("Using a sunday")
+		start, _, err = calendarPeriod("Week", time.Date(2025, 11, 2, 23, 0, 0, 0, berlin))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(start).To(Equal(time.Date(2025, 10, 27, 0, 0, 0, 0, berlin)))
+	})
+
+	It("Should return the current month", func() {
+		start, end, err := calendarPeriod("", time.Date(2025, 10, 15, 12, 0, 0, 0, berlin))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(start).To(Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, berlin)))
+		Expect(end).To(Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, berlin)))
+
+		By("Handling the change from daylight saving time")
+		Expect(end.Sub(start)).To(Equal(31*24*time.Hour + time.Hour))
+	})
+
+	It("Should return the current quarter", func() {
+		start, end, err := calendarPeriod("Quarter", time.Date(2025, 12, 31, 23, 59, 0, 0, berlin))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(start).To(Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, berlin)))
+		Expect(end).To(Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, berlin)))
+	})
+
+	It("Should fail for an invalid period", func() {
+		_, _, err := calendarPeriod("year", time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ServiceLevelObjective Controller (Calendar)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-calendar",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			timeNow = func() time.Time {
+				return time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+			}
+			DeferCleanup(func() {
+				timeNow = time.Now
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-calendar",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+							Window: ricobergerdev1alpha1.Window{
+								Type:     "Calendar",
+								Period:   "Month",
+								Timezone: "Europe/Berlin",
+							},
+						},
+						{
+							Name:      "latency",
+							Objective: "95",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}])) - sum(rate(http_request_duration_seconds_bucket{job="api",le="0.5"}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			})
+		})
+
+		It("Should generate the rules for the current period", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			berlin, _ := time.LoadLocation("Europe/Berlin")
+			start := time.Date(2025, 10, 1, 0, 0, 0, 0, berlin)
+			end := time.Date(2025, 11, 1, 0, 0, 0, 0, berlin)
+			Expect(result.RequeueAfter).To(Equal(end.Sub(timeNow()) + time.Second))
+
+			By("Checking the generated rules")
+			prometheusRule := &monitoringv1.PrometheusRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+
+			exprs := make(map[string]string)
+			for _, group := range prometheusRule.Spec.Groups {
+				for _, rule := range group.Rules {
+					if rule.Record != "" && rule.Labels["window"] == "" {
+						exprs[rule.Labels["slo"]+":"+rule.Record] = rule.Expr.String()
+					}
+				}
+			}
+			Expect(exprs).To(HaveKeyWithValue("availability:slo:window", "2682000"))
+			Expect(exprs).To(HaveKeyWithValue("availability:slo:availability", `1 - sum_over_time(slo:errors_total{id="test-calendar-default-availability"}[2682000s] @ 1761951600) / sum_over_time(slo:total{id="test-calendar-default-availability"}[2682000s] @ 1761951600)`))
+			Expect(exprs).To(HaveKeyWithValue("availability:slo:error_budget_remaining_events", `(1-0.99) * avg_over_time(slo:total{id="test-calendar-default-availability"}[2682000s] @ 1761951600) * 2682000 - avg_over_time(slo:errors_total{id="test-calendar-default-availability"}[2682000s] @ 1761951600) * (time() - 1759269600)`))
+
+			By("Only counting the errors of the elapsed part of the period")
+			// With 100 events and 0.5 errors per second the allowed errors for
+			// the whole period are 2682000, while only the errors of the first
+			// half of the period (670500) are consumed in the middle of it.
+			expr := strings.NewReplacer(
+				`avg_over_time(slo:total{id="test-calendar-default-availability"}[2682000s] @ 1761951600)`, "100",
+				`avg_over_time(slo:errors_total{id="test-calendar-default-availability"}[2682000s] @ 1761951600)`, "0.5",
+				"time()", strconv.FormatInt(start.Unix()+2682000/2, 10),
+			).Replace(exprs["availability:slo:error_budget_remaining_events"])
+			remaining, err := metricsql.Parse(expr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remaining).To(BeAssignableToTypeOf(&metricsql.NumberExpr{}))
+			Expect(remaining.(*metricsql.NumberExpr).N).To(BeNumerically("~", 2682000-670500, 0.001))
+			Expect(exprs).To(HaveKeyWithValue("latency:slo:window", "2419200"))
+			Expect(exprs).To(HaveKeyWithValue("latency:slo:availability", `1 - sum_over_time(slo:errors_total{id="test-calendar-default-latency"}[28d]) / sum_over_time(slo:total{id="test-calendar-default-latency"}[28d])`))
+
+			By("Checking the status of the resource")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.SLOs).To(HaveLen(2))
+			Expect(resource.Status.SLOs[0].PeriodStart.Time.Equal(start)).To(BeTrue())
+			Expect(resource.Status.SLOs[0].PeriodEnd.Time.Equal(end)).To(BeTrue())
+			Expect(resource.Status.SLOs[1].PeriodStart).To(BeNil())
+			Expect(resource.Status.SLOs[1].PeriodEnd).To(BeNil())
+		})
+
+		It("Should record the traffic ratio over the current period", func() {
+			groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+				Name:      "availability",
+				Objective: "99",
+				SLI: ricobergerdev1alpha1.SLI{
+					TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+					ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+				},
+				Window: ricobergerdev1alpha1.Window{
+					Type: "Calendar",
+				},
+				Alerting: ricobergerdev1alpha1.Alerting{
+					BurnRateType: "Dynamic",
+				},
+			}, map[string]string{"name": "test", "namespace": "default"}, promQL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups[1].Rules[7].Record).To(Equal("slo:traffic_ratio"))
+			Expect(groups[1].Rules[7].Expr.String()).To(Equal(`avg_over_time(slo:total{id="test-default-availability"}[2678400s] @ 1761955200) / avg_over_time(slo:total{id="test-default-availability"}[1h])`))
+		})
+
+		It("Should use the calendar window for LogQL SLIs", func() {
+			groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+				Name:      "availability",
+				Objective: "99",
+				SLI: ricobergerdev1alpha1.SLI{
+					Type:       "LogQL",
+					TotalQuery: `sum(count_over_time({app="api"}[${window}]))`,
+					ErrorQuery: `sum(count_over_time({app="api"} |= "error" [${window}]))`,
+				},
+				Window: ricobergerdev1alpha1.Window{
+					Type: "Calendar",
+				},
+			}, map[string]string{"name": "test", "namespace": "default"}, logQL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(3))
+			Expect(groups[2].Rules[2].Record).To(Equal("slo:availability"))
+			Expect(groups[2].Rules[2].Expr.String()).To(Equal(`1 - sum_over_time(slo:errors_total{id="test-default-availability"}[2678400s] @ 1761955200) / sum_over_time(slo:total{id="test-default-availability"}[2678400s] @ 1761955200)`))
+		})
+	})
+})
@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// compositeSLO is a resolved child SLO of a composite SLO.
+type compositeSLO struct {
+	id     string
+	weight float64
+}
+
+// generateCompositeRuleGroup generates the Prometheus rule groups for a
+// composite SLO, which combines the recorded "slo:availability" and
+// "slo:burnrate" metrics of other SLOs.
+//
+// For the "WeightedAverage" type the availability and burn rates are the
+// weighted average of the child SLOs. For the "Product" type, which should be
+// used for serial dependencies, the availability is the product of the
+// availabilities of the child SLOs and the burn rates are calculated via
+// "1 - (1 - burnrate1) * (1 - burnrate2) ...".
+//
+// The metrics of the child SLOs are aggregated via "min" (availability) and
+// "max" (burn rates), so that their labels are removed and the worst group is
+// used, when a child SLO is grouped by labels.
+//
+// Since the composite SLO has no total and error query, the "slo:total" and
+// "slo:errors_total" metrics are not recorded and the options for the burn
+// rate alerts, which are based on the traffic, are not supported. The
+// "SLOMetricAbsent" alert fires when the availability of one of the child SLOs
+// is absent.
+func generateCompositeRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string]string) ([]monitoringv1.RuleGroup, error) {
+	if slo.Name == "" || slo.Objective == "" || len(slo.Composite.SLOs) == 0 {
+		return nil, fmt.Errorf("required field name, objective or composite slos is missing")
+	}
+
+	if slo.Alerting.MinimumEvents > 0 || slo.Alerting.TrafficScaledThresholds || strings.EqualFold(slo.Alerting.BurnRateType, "dynamic") || len(slo.GroupBy) > 0 || len(slo.Objectives) > 0 {
+		return nil, fmt.Errorf("minimum events, traffic-scaled thresholds, dynamic burn rates, group by labels and additional objectives are not supported for composite SLOs")
+	}
+
+	id := generateSLOID(labels, slo.Name)
+
+	sloLabels := make(map[string]string)
+	maps.Copy(sloLabels, labels)
+	sloLabels["id"] = id
+	sloLabels["slo"] = slo.Name
+
+	objective, err := strconv.ParseFloat(slo.Objective, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SLO objective: %w", err)
+	}
+	objective = objective / 100.0
+
+	sloWindow, err := generateSLOWindow(slo.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	children, totalWeight, err := resolveCompositeSLOs(slo.Composite, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	product := strings.EqualFold(slo.Composite.Type, "product")
+
+	// combine returns the combined expression of the provided metric of all
+	// child SLOs. For the burn rates the error ratio of the child SLOs is
+	// combined, so that the product is calculated from the availability.
+	combine := func(metric, window string, errorRatio bool) string {
+		aggregation := "min"
+		if errorRatio {
+			aggregation = "max"
+		}
+
+		selector := func(child compositeSLO) string {
+			if window != "" {
+				return fmt.Sprintf(`%s(%s{window="%s", id="%s"})`, aggregation, metric, window, child.id)
+			}
+			return fmt.Sprintf(`%s(%s{id="%s"})`, aggregation, metric, child.id)
+		}
+
+		parts := make([]string, 0, len(children))
+		for _, child := range children {
+			switch {
+			case product && errorRatio:
+				parts = append(parts, fmt.Sprintf("(1 - %s)", selector(child)))
+			case product:
+				parts = append(parts, selector(child))
+			default:
+				parts = append(parts, fmt.Sprintf("%s * %s", strconv.FormatFloat(child.weight, 'f', -1, 64), selector(child)))
+			}
+		}
+
+		switch {
+		case product && errorRatio:
+			return fmt.Sprintf("1 - %s", strings.Join(parts, " * "))
+		case product:
+			return strings.Join(parts, " * ")
+		default:
+			return fmt.Sprintf("(%s) / %s", strings.Join(parts, " + "), strconv.FormatFloat(totalWeight, 'f', -1, 64))
+		}
+	}
+
+	genericRules := []monitoringv1.Rule{
+		{
+			Record: "slo:window",
+			Expr:   intstr.FromInt(int(sloWindow.Seconds)),
+			Labels: sloLabels,
+		},
+		{
+			Record: "slo:objective",
+			Expr:   intstr.FromString(strconv.FormatFloat(objective, 'f', -1, 64)),
+			Labels: sloLabels,
+		},
+		{
+			Record: "slo:availability",
+			Expr:   intstr.FromString(combine("slo:availability", "", false)),
+			Labels: sloLabels,
+		},
+	}
+
+	var errorsRules []monitoringv1.Rule
+	for _, window := range []string{"5m", "30m", "1h", "2h", "6h", "1d", "4d"} {
+		recordLabels := make(map[string]string)
+		maps.Copy(recordLabels, sloLabels)
+		recordLabels["window"] = window
+
+		errorsRules = append(errorsRules, monitoringv1.Rule{
+			Record: "slo:burnrate",
+			Expr:   intstr.FromString(combine("slo:burnrate", window, true)),
+			Labels: recordLabels,
+		})
+	}
+
+	if !slo.Alerting.Disabled {
+		severities := generateSeverities(slo.Alerting)
+
+		if slo.Alerting.Absent == nil || !slo.Alerting.Absent.Disabled {
+			// If the user didn't provide an own query or selectors for the
+			// absent alert, the availability of all child SLOs is checked.
+			absent := &ricobergerdev1alpha1.AbsentAlerting{}
+			if slo.Alerting.Absent != nil {
+				absent = slo.Alerting.Absent.DeepCopy()
+			}
+			if absent.Query == "" && len(absent.Selectors) == 0 {
+				for _, child := range children {
+					absent.Selectors = append(absent.Selectors, fmt.Sprintf(`slo:availability{id="%s"}`, child.id))
+				}
+			}
+			absent.ErrorQuery = false
+
+			absentRule, err := generatePrometheusRuleAbsentAlerting(ricobergerdev1alpha1.SLI{}, absent, sloLabels, severities[0])
+			if err != nil {
+				return nil, err
+			}
+			genericRules = append(genericRules, absentRule)
+		}
+
+		errorsRules = append(errorsRules, generatePrometheusRuleBurnRateAlerts(slo.Alerting, id, sloLabels, objective, severities)...)
+	}
+
+	genericGroup, err := generatePrometheusRuleGroupWithOptions(fmt.Sprintf("slo-generic-%s", id), genericRules, slo.RuleGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	errorsGroup, err := generatePrometheusRuleGroupWithOptions(fmt.Sprintf("slo-errors-%s", id), errorsRules, slo.RuleGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return []monitoringv1.RuleGroup{genericGroup, errorsGroup}, nil
+}
+
+// resolveCompositeSLOs returns the ids and weights of the child SLOs of a
+// composite SLO and the sum of all weights. If the ServiceLevelObjective or
+// namespace of a child SLO is not set, the ServiceLevelObjective / namespace
+// of the composite SLO is used.
+func resolveCompositeSLOs(composite *ricobergerdev1alpha1.Composite, labels map[string]string) ([]compositeSLO, float64, error) {
+	var children []compositeSLO
+	var totalWeight float64
+
+	for _, child := range composite.SLOs {
+		if child.Name == "" {
+			return nil, 0, fmt.Errorf("required field name of composite slo is missing")
+		}
+
+		childLabels := map[string]string{"name": labels["name"], "namespace": labels["namespace"]}
+		if child.ServiceLevelObjective != "" {
+			childLabels["name"] = child.ServiceLevelObjective
+		}
+		if child.Namespace != "" {
+			childLabels["namespace"] = child.Namespace
+		}
+
+		weight := 1.0
+		if child.Weight != "" {
+			var err error
+			weight, err = strconv.ParseFloat(child.Weight, 64)
+			if err != nil || weight <= 0 {
+				return nil, 0, fmt.Errorf("weight of composite slo %s must be a positive number", child.Name)
+			}
+		}
+
+		children = append(children, compositeSLO{id: generateSLOID(childLabels, child.Name), weight: weight})
+		totalWeight = totalWeight + weight
+	}
+
+	return children, totalWeight, nil
+}
+
+// validateCompositeSLOs checks that all child SLOs of the composite SLOs of a
+// ServiceLevelObjective exist, so that the user gets an error for a typo in a
+// reference instead of an absent alert.
+func (r *ServiceLevelObjectiveReconciler) validateCompositeSLOs(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) error {
+	for _, s := range slo.Spec.SLOs {
+		if s.Composite == nil {
+			continue
+		}
+
+		for _, child := range s.Composite.SLOs {
+			name := types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}
+			if child.ServiceLevelObjective != "" {
+				name.Name = child.ServiceLevelObjective
+			}
+			if child.Namespace != "" {
+				name.Namespace = child.Namespace
+			}
+
+			childSLOs := slo.Spec.SLOs
+			if name.Name != slo.Name || name.Namespace != slo.Namespace {
+				childServiceLevelObjective := &ricobergerdev1alpha1.ServiceLevelObjective{}
+				if err := r.Get(ctx, name, childServiceLevelObjective); err != nil {
+					return fmt.Errorf("failed to get ServiceLevelObjective %s of composite slo %s: %w", name, s.Name, err)
+				}
+				childSLOs = childServiceLevelObjective.Spec.SLOs
+			}
+
+			if !slices.ContainsFunc(childSLOs, func(childSLO ricobergerdev1alpha1.SLO) bool {
+				return childSLO.Name == child.Name && (childSLO.Name != s.Name || name.Name != slo.Name || name.Namespace != slo.Namespace)
+			}) {
+				return fmt.Errorf("slo %s of composite slo %s not found in ServiceLevelObjective %s", child.Name, s.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// compositeServiceLevelObjectiveRefField is the name of the field index, which
+// contains all other ServiceLevelObjectives referenced by the composite SLOs of
+// a ServiceLevelObjective in the form "<namespace>/<name>".
+const compositeServiceLevelObjectiveRefField = ".spec.slos.composite.slos.serviceLevelObjective"
+
+// indexCompositeServiceLevelObjectiveRefs returns the values for the
+// compositeServiceLevelObjectiveRefField index of the provided
+// ServiceLevelObjective. References to the ServiceLevelObjective itself are
+// skipped, because they are already covered by its own events.
+func indexCompositeServiceLevelObjectiveRefs(obj client.Object) []string {
+	slo, ok := obj.(*ricobergerdev1alpha1.ServiceLevelObjective)
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+	for _, s := range slo.Spec.SLOs {
+		if s.Composite == nil {
+			continue
+		}
+
+		for _, child := range s.Composite.SLOs {
+			name := types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}
+			if child.ServiceLevelObjective != "" {
+				name.Name = child.ServiceLevelObjective
+			}
+			if child.Namespace != "" {
+				name.Namespace = child.Namespace
+			}
+
+			if name.Name == slo.Name && name.Namespace == slo.Namespace {
+				continue
+			}
+
+			if ref := name.String(); !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// findCompositeServiceLevelObjectivesForServiceLevelObjective returns a
+// reconcile request for each ServiceLevelObjective, which contains a composite
+// SLO referencing a SLO of the provided ServiceLevelObjective, so that the
+// references of the composite SLOs are validated again when the child SLOs are
+// changed or deleted. The ServiceLevelObjectives are looked up via the
+// compositeServiceLevelObjectiveRefField index.
+func (r *ServiceLevelObjectiveReconciler) findCompositeServiceLevelObjectivesForServiceLevelObjective(ctx context.Context, slo client.Object) []reconcile.Request {
+	serviceLevelObjectives := &ricobergerdev1alpha1.ServiceLevelObjectiveList{}
+	if err := r.List(ctx, serviceLevelObjectives, client.MatchingFields{compositeServiceLevelObjectiveRefField: client.ObjectKeyFromObject(slo).String()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ServiceLevelObjectives.")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, composite := range serviceLevelObjectives.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: composite.Name, Namespace: composite.Namespace}})
+	}
+
+	return requests
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+	"github.com/ricoberger/slo-operator/assets"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// grafanaDashboardGVK is the GroupVersionKind of the GrafanaDashboard resource
+// of the grafana-operator. We are using an unstructured object for the
+// resource, so that we do not have to import the API of the grafana-operator.
+var grafanaDashboardGVK = schema.GroupVersionKind{
+	Group:   "grafana.integreatly.org",
+	Version: "v1beta1",
+	Kind:    "GrafanaDashboard",
+}
+
+// reconcileDashboard creates / updates the Grafana dashboard for a
+// ServiceLevelObjective resource. The dashboard is only created when the
+// "SLO_OPERATOR_DASHBOARD_MODE" environment variable is set to "configmap" or
+// "grafanaoperator".
+//
+// For the "configmap" mode the dashboard is saved in a ConfigMap with the
+// "grafana_dashboard: 1" label, so that it can be picked up by the Grafana
+// sidecar. For the "grafanaoperator" mode a GrafanaDashboard resource is
+// created, which selects the Grafana instances via the labels configured in
+// the "SLO_OPERATOR_DASHBOARD_INSTANCE_SELECTOR" environment variable (default
+// "dashboards=grafana").
+func (r *ServiceLevelObjectiveReconciler) reconcileDashboard(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, labels map[string]string) error {
+	if sloOperatorDashboardMode != "configmap" && sloOperatorDashboardMode != "grafanaoperator" {
+		return nil
+	}
+
+	dashboard, err := generateDashboard(slo, labels)
+	if err != nil {
+		return err
+	}
+
+	if sloOperatorDashboardMode == "grafanaoperator" {
+		return r.reconcileGrafanaDashboard(ctx, slo, dashboard)
+	}
+	return r.reconcileDashboardConfigMap(ctx, slo, dashboard)
+}
+
+// reconcileDashboardConfigMap creates / updates the ConfigMap with the Grafana
+// dashboard for a ServiceLevelObjective resource. The ConfigMap is named
+// "<name>-dashboard" and contains the dashboard in the
+// "<namespace>-<name>.json" key.
+func (r *ServiceLevelObjectiveReconciler) reconcileDashboardConfigMap(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, dashboard []byte) error {
+	reqLogger := log.FromContext(ctx)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-dashboard", slo.Name),
+			Namespace: slo.Namespace,
+			Labels: map[string]string{
+				"grafana_dashboard": "1",
+			},
+		},
+		Data: map[string]string{
+			fmt.Sprintf("%s-%s.json", slo.Namespace, slo.Name): string(dashboard),
+		},
+	}
+
+	err := ctrl.SetControllerReference(slo, configMap, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	found := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new dashboard ConfigMap.")
+		err = r.Create(ctx, configMap)
+		if err != nil {
+			reqLogger.Error(err, "Failed to create dashboard ConfigMap.")
+			return err
+		}
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if maps.Equal(found.Data, configMap.Data) && maps.Equal(found.Labels, configMap.Labels) {
+		return nil
+	}
+
+	reqLogger.Info("Updating an existing dashboard ConfigMap.")
+	configMap.ResourceVersion = found.ResourceVersion
+
+	err = r.Update(ctx, configMap)
+	if err != nil {
+		reqLogger.Error(err, "Failed to update dashboard ConfigMap.")
+		return err
+	}
+
+	return nil
+}
+
+// reconcileGrafanaDashboard creates / updates the GrafanaDashboard resource for
+// a ServiceLevelObjective resource. The GrafanaDashboard has the same name as
+// the ServiceLevelObjective.
+func (r *ServiceLevelObjectiveReconciler) reconcileGrafanaDashboard(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, dashboard []byte) error {
+	reqLogger := log.FromContext(ctx)
+
+	selector := sloOperatorDashboardInstanceSelector
+	if selector == "" {
+		selector = "dashboards=grafana"
+	}
+
+	instanceSelector, err := metav1.ParseToLabelSelector(selector)
+	if err != nil {
+		return fmt.Errorf("failed to parse dashboard instance selector: %w", err)
+	}
+	matchLabels := make(map[string]any)
+	for k, v := range instanceSelector.MatchLabels {
+		matchLabels[k] = v
+	}
+
+	grafanaDashboard := &unstructured.Unstructured{}
+	grafanaDashboard.SetGroupVersionKind(grafanaDashboardGVK)
+	grafanaDashboard.SetName(slo.Name)
+	grafanaDashboard.SetNamespace(slo.Namespace)
+	grafanaDashboard.Object["spec"] = map[string]any{
+		"allowCrossNamespaceImport": true,
+		"instanceSelector": map[string]any{
+			"matchLabels": matchLabels,
+		},
+		"json": string(dashboard),
+	}
+
+	err = ctrl.SetControllerReference(slo, grafanaDashboard, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(grafanaDashboardGVK)
+	err = r.Get(ctx, types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new GrafanaDashboard.")
+		err = r.Create(ctx, grafanaDashboard)
+		if err != nil {
+			reqLogger.Error(err, "Failed to create GrafanaDashboard.")
+			return err
+		}
+
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(found.Object["spec"], grafanaDashboard.Object["spec"]) {
+		return nil
+	}
+
+	reqLogger.Info("Updating an existing GrafanaDashboard.")
+	grafanaDashboard.SetResourceVersion(found.GetResourceVersion())
+
+	err = r.Update(ctx, grafanaDashboard)
+	if err != nil {
+		reqLogger.Error(err, "Failed to update GrafanaDashboard.")
+		return err
+	}
+
+	return nil
+}
+
+// generateDashboard generates the Grafana dashboard for a ServiceLevelObjective
+// resource from the "servicelevelobjective.json" dashboard in the assets
+// folder.
+//
+// The "namespace", "name" and "slo" variables of the dashboard are replaced by
+// a single "id" variable, which contains the ids of all SLOs of the
+// ServiceLevelObjective, so that the dashboard only shows these SLOs. The uid
+// of the dashboard is derived from the namespace and name of the
+// ServiceLevelObjective, so that it is stable across reconciliations.
+func generateDashboard(slo *ricobergerdev1alpha1.ServiceLevelObjective, labels map[string]string) ([]byte, error) {
+	template := strings.ReplaceAll(string(assets.ServiceLevelObjectiveDashboard), `namespace=\"$namespace\", name=\"$name\", slo=\"$slo\"`, `id=\"$id\"`)
+
+	var dashboard map[string]any
+	if err := json.Unmarshal([]byte(template), &dashboard); err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard template: %w", err)
+	}
+
+	var options []any
+	var query []string
+	for _, s := range slo.Spec.SLOs {
+		id := generateSLOID(labels, s.Name)
+		options = append(options, map[string]any{
+			"selected": len(options) == 0,
+			"text":     s.Name,
+			"value":    id,
+		})
+		query = append(query, fmt.Sprintf("%s : %s", s.Name, id))
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no slos defined")
+	}
+
+	variables := []any{
+		map[string]any{
+			"label": "Datasource",
+			"name":  "datasource",
+			"query": "prometheus",
+			"type":  "datasource",
+		},
+		map[string]any{
+			"current": options[0],
+			"label":   "SLO",
+			"name":    "id",
+			"options": options,
+			"query":   strings.Join(query, ","),
+			"type":    "custom",
+		},
+	}
+
+	dashboard["templating"] = map[string]any{"list": variables}
+	dashboard["title"] = fmt.Sprintf("Service Level Objective / %s / %s", slo.Namespace, slo.Name)
+	dashboard["uid"] = fmt.Sprintf("slo-%x", sha256.Sum256([]byte(fmt.Sprintf("%s/%s", slo.Namespace, slo.Name))))[:24]
+	dashboard["tags"] = []string{"slo-operator"}
+	delete(dashboard, "id")
+	delete(dashboard, "version")
+
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ServiceLevelObjective Controller (Dashboard)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-dashboard",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			sloOperatorDashboardMode = "configmap"
+			DeferCleanup(func() {
+				sloOperatorDashboardMode = ""
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-dashboard",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			})
+		})
+
+		It("Should create and update the dashboard ConfigMap", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-dashboard-dashboard", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Labels).To(HaveKeyWithValue("grafana_dashboard", "1"))
+			Expect(configMap.OwnerReferences).To(HaveLen(1))
+			Expect(configMap.Data).To(HaveKey("default-test-dashboard.json"))
+			Expect(configMap.Data["default-test-dashboard.json"]).To(ContainSubstring(`test-dashboard-default-availability`))
+
+			By("Adding a SLO to the resource")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.SLOs = append(resource.Spec.SLOs, ricobergerdev1alpha1.SLO{
+				Name:      "latency",
+				Objective: "99",
+				SLI:       resource.Spec.SLOs[0].SLI,
+			})
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-dashboard-dashboard", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Data["default-test-dashboard.json"]).To(ContainSubstring(`test-dashboard-default-latency`))
+		})
+
+		It("Should create the GrafanaDashboard", func() {
+			sloOperatorDashboardMode = "grafanaoperator"
+			sloOperatorDashboardInstanceSelector = "dashboards=slo"
+			DeferCleanup(func() {
+				sloOperatorDashboardInstanceSelector = ""
+			})
+
+			By("Reconciling the created resource")
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			grafanaDashboard := &unstructured.Unstructured{}
+			grafanaDashboard.SetGroupVersionKind(schema.GroupVersionKind{
+				Group:   "grafana.integreatly.org",
+				Version: "v1beta1",
+				Kind:    "GrafanaDashboard",
+			})
+			Expect(k8sClient.Get(ctx, typeNamespacedName, grafanaDashboard)).To(Succeed())
+			Expect(grafanaDashboard.GetOwnerReferences()).To(HaveLen(1))
+
+			matchLabels, _, err := unstructured.NestedStringMap(grafanaDashboard.Object, "spec", "instanceSelector", "matchLabels")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matchLabels).To(Equal(map[string]string{"dashboards": "slo"}))
+
+			dashboard, _, err := unstructured.NestedString(grafanaDashboard.Object, "spec", "json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dashboard).To(ContainSubstring(`test-dashboard-default-availability`))
+		})
+	})
+})
+
+var _ = Describe("generateDashboard", func() {
+	It("Should filter the dashboard by the SLO ids", func() {
+		dashboard, err := generateDashboard(&ricobergerdev1alpha1.ServiceLevelObjective{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "grafana",
+				Namespace: "monitoring",
+			},
+			Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+				SLOs: []ricobergerdev1alpha1.SLO{
+					{Name: "availability"},
+					{Name: "latency"},
+				},
+			},
+		}, map[string]string{"name": "grafana", "namespace": "monitoring"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dashboard)).NotTo(ContainSubstring("$namespace"))
+		Expect(string(dashboard)).NotTo(ContainSubstring("$slo"))
+
+		var parsed struct {
+			Title      string `json:"title"`
+			UID        string `json:"uid"`
+			Templating struct {
+				List []struct {
+					Name  string `json:"name"`
+					Query string `json:"query"`
+				} `json:"list"`
+			} `json:"templating"`
+			Panels []struct {
+				Targets []struct {
+					Expr string `json:"expr"`
+				} `json:"targets"`
+			} `json:"panels"`
+		}
+		Expect(json.Unmarshal(dashboard, &parsed)).To(Succeed())
+		Expect(parsed.Title).To(Equal("Service Level Objective / monitoring / grafana"))
+		Expect(parsed.UID).To(HaveLen(24))
+		Expect(parsed.Templating.List).To(HaveLen(2))
+		Expect(parsed.Templating.List[1].Name).To(Equal("id"))
+		Expect(parsed.Templating.List[1].Query).To(Equal("availability : grafana-monitoring-availability,latency : grafana-monitoring-latency"))
+
+		for _, panel := range parsed.Panels {
+			for _, target := range panel.Targets {
+				Expect(strings.Contains(target.Expr, `id="$id"`)).To(BeTrue(), target.Expr)
+			}
+		}
+	})
+})
@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// grafanaAlertRuleFile is a Grafana alert rule provisioning file.
+//
+// See https://grafana.com/docs/grafana/latest/alerting/set-up/provision-alerting-resources/file-provisioning/
+type grafanaAlertRuleFile struct {
+	APIVersion  int                      `json:"apiVersion"`
+	Groups      []grafanaAlertRuleGroup  `json:"groups"`
+	DeleteRules []grafanaAlertRuleDelete `json:"deleteRules,omitempty"`
+}
+
+type grafanaAlertRuleGroup struct {
+	OrgID    int                `json:"orgId"`
+	Name     string             `json:"name"`
+	Folder   string             `json:"folder"`
+	Interval string             `json:"interval"`
+	Rules    []grafanaAlertRule `json:"rules"`
+}
+
+type grafanaAlertRule struct {
+	UID          string             `json:"uid"`
+	Title        string             `json:"title"`
+	Condition    string             `json:"condition"`
+	Data         []grafanaAlertData `json:"data"`
+	For          string             `json:"for,omitempty"`
+	NoDataState  string             `json:"noDataState"`
+	ExecErrState string             `json:"execErrState"`
+	Labels       map[string]string  `json:"labels,omitempty"`
+	Annotations  map[string]string  `json:"annotations,omitempty"`
+}
+
+type grafanaAlertData struct {
+	RefID             string                   `json:"refId"`
+	RelativeTimeRange grafanaAlertRelativeTime `json:"relativeTimeRange"`
+	DatasourceUID     string                   `json:"datasourceUid"`
+	Model             map[string]any           `json:"model"`
+}
+
+type grafanaAlertRelativeTime struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type grafanaAlertRuleDelete struct {
+	OrgID int    `json:"orgId"`
+	UID   string `json:"uid"`
+}
+
+// splitAlertingRules splits the provided rule groups into groups which only
+// contain the recording rules and groups which only contain the alerting
+// rules. Groups without alerting rules are not contained in the returned
+// alerting groups.
+func splitAlertingRules(groups []monitoringv1.RuleGroup) ([]monitoringv1.RuleGroup, []monitoringv1.RuleGroup) {
+	var recordingGroups, alertingGroups []monitoringv1.RuleGroup
+
+	for _, group := range groups {
+		recordingGroup := group
+		recordingGroup.Rules = nil
+		alertingGroup := group
+		alertingGroup.Rules = nil
+
+		for _, rule := range group.Rules {
+			if rule.Alert != "" {
+				alertingGroup.Rules = append(alertingGroup.Rules, rule)
+			} else {
+				recordingGroup.Rules = append(recordingGroup.Rules, rule)
+			}
+		}
+
+		recordingGroups = append(recordingGroups, recordingGroup)
+		if len(alertingGroup.Rules) > 0 {
+			alertingGroups = append(alertingGroups, alertingGroup)
+		}
+	}
+
+	return recordingGroups, alertingGroups
+}
+
+// generateGrafanaAlertRuleGroups converts the provided Prometheus rule groups,
+// which should only contain alerting rules, to Grafana alert rule groups.
+//
+// Each alert queries the configured Prometheus datasource. Since the
+// expressions of our alerts only return a result, when the alert should fire,
+// the condition of the Grafana alert is a math expression, which is true for
+// every returned series, independent of its value. A threshold like "> 0" would
+// never fire for alerts returning negative values, e.g. the remaining error
+// budget of the "SLOErrorBudgetExhausted" alert. When the query doesn't return
+// a result, the alert is resolved.
+//
+// The uid of each alert is derived from the group name and the index of the
+// rule in the group, so that it is stable across reconciliations. The title of
+// an alert is the name of the Prometheus alert, because Grafana uses the title
+// as "alertname" label, which is used by notification policies and inhibit
+// rules. This requires a Grafana version, which doesn't enforce unique titles
+// within a folder.
+func generateGrafanaAlertRuleGroups(groups []monitoringv1.RuleGroup, orgID int, datasourceUID, folder string) []grafanaAlertRuleGroup {
+	grafanaGroups := make([]grafanaAlertRuleGroup, 0, len(groups))
+
+	for _, group := range groups {
+		grafanaGroup := grafanaAlertRuleGroup{
+			OrgID:    orgID,
+			Name:     group.Name,
+			Folder:   folder,
+			Interval: "30s",
+		}
+		if group.Interval != nil {
+			grafanaGroup.Interval = string(*group.Interval)
+		}
+
+		for i, rule := range group.Rules {
+			labels := make(map[string]string)
+			maps.Copy(labels, rule.Labels)
+			maps.Copy(labels, group.Labels)
+
+			grafanaRule := grafanaAlertRule{
+				UID:       fmt.Sprintf("%x", sha256.Sum256(fmt.Appendf(nil, "%s/%d", group.Name, i)))[:40],
+				Title:     rule.Alert,
+				Condition: "B",
+				Data: []grafanaAlertData{
+					{
+						RefID:             "A",
+						RelativeTimeRange: grafanaAlertRelativeTime{From: 600},
+						DatasourceUID:     datasourceUID,
+						Model: map[string]any{
+							"refId":   "A",
+							"expr":    rule.Expr.String(),
+							"instant": true,
+						},
+					},
+					{
+						RefID:             "B",
+						RelativeTimeRange: grafanaAlertRelativeTime{From: 600},
+						DatasourceUID:     "__expr__",
+						Model: map[string]any{
+							"refId":      "B",
+							"type":       "math",
+							"expression": "is_number($A) || is_nan($A) || is_inf($A)",
+							"datasource": map[string]any{"type": "__expr__", "uid": "__expr__"},
+						},
+					},
+				},
+				NoDataState:  "OK",
+				ExecErrState: "Error",
+				Labels:       labels,
+				Annotations:  rule.Annotations,
+			}
+			if rule.For != nil {
+				grafanaRule.For = string(*rule.For)
+			}
+
+			grafanaGroup.Rules = append(grafanaGroup.Rules, grafanaRule)
+		}
+
+		grafanaGroups = append(grafanaGroups, grafanaGroup)
+	}
+
+	return grafanaGroups
+}
+
+// reconcileGrafanaAlerts creates / updates a ConfigMap, which contains the
+// alerts for a ServiceLevelObjective resource as Grafana alert rule
+// provisioning file. The ConfigMap is named "<name>-alerts", contains the file
+// in the "<namespace>-<name>.yaml" key and has the "grafana_alert: 1" label, so
+// that it can be picked up by the Grafana sidecar.
+//
+// Grafana doesn't delete provisioned alerts, when they are removed from a
+// provisioning file. Therefore we compare the uids of the alerts in the
+// existing ConfigMap with the new alerts and add all removed alerts to the
+// "deleteRules" list of the file.
+func (r *ServiceLevelObjectiveReconciler) reconcileGrafanaAlerts(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup) error {
+	reqLogger := log.FromContext(ctx)
+
+	if sloOperatorGrafanaDatasourceUID == "" {
+		return fmt.Errorf("the grafana alerting mode requires the SLO_OPERATOR_GRAFANA_DATASOURCE_UID environment variable")
+	}
+
+	folder := sloOperatorGrafanaFolder
+	if folder == "" {
+		folder = "SLOs"
+	}
+
+	orgID := 1
+	if sloOperatorGrafanaOrgID != "" {
+		id, err := strconv.Atoi(sloOperatorGrafanaOrgID)
+		if err != nil {
+			return fmt.Errorf("invalid SLO_OPERATOR_GRAFANA_ORG_ID environment variable: %w", err)
+		}
+		orgID = id
+	}
+
+	key := fmt.Sprintf("%s-%s.yaml", slo.Namespace, slo.Name)
+	file := grafanaAlertRuleFile{
+		APIVersion: 1,
+		Groups:     generateGrafanaAlertRuleGroups(groups, orgID, sloOperatorGrafanaDatasourceUID, folder),
+	}
+
+	found := &corev1.ConfigMap{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-alerts", slo.Name), Namespace: slo.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		file.DeleteRules = getDeletedGrafanaAlertRules(found.Data[key], file.Groups)
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-alerts", slo.Name),
+			Namespace: slo.Namespace,
+			Labels: map[string]string{
+				"grafana_alert": "1",
+			},
+		},
+		Data: map[string]string{
+			key: string(data),
+		},
+	}
+
+	err = ctrl.SetControllerReference(slo, configMap, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	if found.ResourceVersion == "" {
+		reqLogger.Info("Creating a new Grafana alerts ConfigMap.")
+		err = r.Create(ctx, configMap)
+		if err != nil {
+			reqLogger.Error(err, "Failed to create Grafana alerts ConfigMap.")
+			return err
+		}
+
+		return nil
+	}
+
+	if maps.Equal(found.Data, configMap.Data) && maps.Equal(found.Labels, configMap.Labels) {
+		return nil
+	}
+
+	reqLogger.Info("Updating an existing Grafana alerts ConfigMap.")
+	configMap.ResourceVersion = found.ResourceVersion
+
+	err = r.Update(ctx, configMap)
+	if err != nil {
+		reqLogger.Error(err, "Failed to update Grafana alerts ConfigMap.")
+		return err
+	}
+
+	return nil
+}
+
+// getDeletedGrafanaAlertRules returns the alerts which are contained in the
+// provided provisioning file, but not in the provided groups. Alerts which
+// were already marked for deletion in the file are kept, until they are
+// created again.
+func getDeletedGrafanaAlertRules(current string, groups []grafanaAlertRuleGroup) []grafanaAlertRuleDelete {
+	var file grafanaAlertRuleFile
+	if err := yaml.Unmarshal([]byte(current), &file); err != nil {
+		return nil
+	}
+
+	var uids []string
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			uids = append(uids, rule.UID)
+		}
+	}
+
+	var deleteRules []grafanaAlertRuleDelete
+	for _, deleteRule := range file.DeleteRules {
+		if !slices.Contains(uids, deleteRule.UID) {
+			deleteRules = append(deleteRules, deleteRule)
+		}
+	}
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			if !slices.Contains(uids, rule.UID) {
+				deleteRules = append(deleteRules, grafanaAlertRuleDelete{OrgID: group.OrgID, UID: rule.UID})
+			}
+		}
+	}
+
+	return deleteRules
+}
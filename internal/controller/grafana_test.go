@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("ServiceLevelObjective Controller (Grafana Alerting)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-grafana",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			sloOperatorAlertingMode = "grafana"
+			sloOperatorGrafanaDatasourceUID = "prometheus"
+			DeferCleanup(func() {
+				sloOperatorAlertingMode = ""
+				sloOperatorGrafanaDatasourceUID = ""
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-grafana",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			})
+		})
+
+		reconcileResource := func() error {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			return err
+		}
+
+		getAlertRuleFile := func() grafanaAlertRuleFile {
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-grafana-alerts", Namespace: "default"}, configMap)).To(Succeed())
+			Expect(configMap.Labels).To(HaveKeyWithValue("grafana_alert", "1"))
+
+			var file grafanaAlertRuleFile
+			Expect(yaml.Unmarshal([]byte(configMap.Data["default-test-grafana.yaml"]), &file)).To(Succeed())
+			return file
+		}
+
+		It("Should provision the alerts in Grafana", func() {
+			By("Reconciling the created resource")
+			Expect(reconcileResource()).To(Succeed())
+
+			By("Check if the PrometheusRule only contains recording rules")
+			prometheusRule := &monitoringv1.PrometheusRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+			Expect(prometheusRule.Spec.Groups).To(HaveLen(3))
+			for _, group := range prometheusRule.Spec.Groups {
+				for _, rule := range group.Rules {
+					Expect(rule.Alert).To(BeEmpty())
+					Expect(rule.Record).NotTo(BeEmpty())
+				}
+			}
+
+			By("Check if the alerts are provisioned in Grafana")
+			file := getAlertRuleFile()
+			Expect(file.APIVersion).To(Equal(1))
+			Expect(file.Groups).To(HaveLen(2))
+			Expect(file.Groups[0].Name).To(Equal("slo-generic-test-grafana-default-availability"))
+			Expect(file.Groups[0].Folder).To(Equal("SLOs"))
+			Expect(file.Groups[0].Interval).To(Equal("30s"))
+			Expect(file.Groups[0].Rules).To(HaveLen(1))
+			Expect(file.Groups[1].Rules).To(HaveLen(4))
+
+			uids := make(map[string]bool)
+			for _, group := range file.Groups {
+				Expect(group.OrgID).To(Equal(1))
+				for _, rule := range group.Rules {
+					uids[rule.UID] = true
+					Expect(rule.UID).To(HaveLen(40))
+					Expect(rule.Condition).To(Equal("B"))
+					Expect(rule.Data[0].DatasourceUID).To(Equal("prometheus"))
+					Expect(rule.Labels).To(HaveKeyWithValue("id", "test-grafana-default-availability"))
+				}
+			}
+			Expect(uids).To(HaveLen(5))
+
+			rule := file.Groups[1].Rules[0]
+			Expect(rule.Title).To(Equal("SLOErrorBudgetBurn"))
+			Expect(rule.For).To(Equal("2m"))
+			Expect(rule.Labels).To(HaveKeyWithValue("severity", "error"))
+			Expect(rule.Data[0].Model).To(HaveKeyWithValue("expr", `slo:burnrate{window="5m", id="test-grafana-default-availability"} > (14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-grafana-default-availability"} > (14 * (1-0.99))`))
+
+			By("Disabling the alerting")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.SLOs[0].Alerting.Disabled = true
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			file = getAlertRuleFile()
+			Expect(file.Groups).To(BeEmpty())
+			Expect(file.DeleteRules).To(HaveLen(5))
+		})
+	})
+})
+
+var _ = Describe("generateGrafanaAlertRuleGroups", func() {
+	It("Should fire for every returned series of the error budget alerts", func() {
+		groups, err := generateErrorBudgetRuleGroups(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			Alerting: ricobergerdev1alpha1.Alerting{
+				ErrorBudgetThresholds: []ricobergerdev1alpha1.ErrorBudgetThreshold{
+					{Remaining: "50", Severity: "info"},
+					{Remaining: "0", Severity: "warning"},
+				},
+				Forecast: &ricobergerdev1alpha1.Forecast{},
+			},
+		}, map[string]string{"name": "test", "namespace": "default"})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, alertingGroups := splitAlertingRules(groups)
+		grafanaGroups := generateGrafanaAlertRuleGroups(alertingGroups, 2, "prometheus", "SLOs")
+		Expect(grafanaGroups).To(HaveLen(1))
+		Expect(grafanaGroups[0].OrgID).To(Equal(2))
+
+		exprs := make(map[string]string)
+		for _, rule := range grafanaGroups[0].Rules {
+			exprs[rule.Title] = rule.Data[0].Model["expr"].(string)
+
+			Expect(rule.Condition).To(Equal("B"))
+			Expect(rule.Data[1].Model).To(HaveKeyWithValue("type", "math"))
+			Expect(rule.Data[1].Model).To(HaveKeyWithValue("expression", "is_number($A) || is_nan($A) || is_inf($A)"))
+			Expect(rule.Data[1].Model).NotTo(HaveKey("conditions"))
+		}
+		Expect(exprs).To(Equal(map[string]string{
+			"SLOErrorBudgetLow":       `slo:error_budget_remaining{id="test-default-availability"} < 0.5`,
+			"SLOErrorBudgetExhausted": `slo:error_budget_remaining{id="test-default-availability"} <= 0`,
+			"SLOErrorBudgetForecast":  `slo:error_budget_forecast{id="test-default-availability"} < 0`,
+		}))
+	})
+})
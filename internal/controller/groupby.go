@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// groupByLabelRegexp is the regular expression for a valid label name, which
+// can be used in the groupBy list of a SLO.
+var groupByLabelRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedGroupByLabels are the labels, which are set by the operator and
+// therefore can not be used in the groupBy list of a SLO.
+var reservedGroupByLabels = []string{"id", "slo", "name", "namespace", "window", "severity", "threshold", "objective"}
+
+// validateGroupBy validates the groupBy list of a SLO. Each label must be a
+// valid label name, which is not set by the operator, and the provided
+// queries must aggregate by all labels, so that the labels are kept in all
+// recorded metrics and alerts.
+//
+// The queries are parsed via the MetricsQL parser, which is a superset of
+// PromQL, so that it can be used for both query languages.
+func validateGroupBy(groupBy []string, queries ...string) error {
+	for _, label := range groupBy {
+		if !groupByLabelRegexp.MatchString(label) {
+			return fmt.Errorf("invalid group by label %s", label)
+		}
+		if slices.Contains(reservedGroupByLabels, label) {
+			return fmt.Errorf("group by label %s is reserved", label)
+		}
+	}
+
+	for _, query := range queries {
+		expr, err := metricsql.Parse(strings.ReplaceAll(query, "${window}", "5m"))
+		if err != nil {
+			return fmt.Errorf("failed to parse query: %w", err)
+		}
+
+		if !aggregatesBy(expr, groupBy) {
+			return fmt.Errorf("query must aggregate by %s: %s", strings.Join(groupBy, ", "), query)
+		}
+	}
+
+	return nil
+}
+
+// aggregatesBy returns true, when the result of the provided expression
+// contains the provided labels, because all aggregations of the expression
+// are grouped by them. For binary operations and functions all operands, which
+// are not a scalar, must be aggregated by the labels.
+func aggregatesBy(expr metricsql.Expr, labels []string) bool {
+	switch e := expr.(type) {
+	case *metricsql.AggrFuncExpr:
+		if e.Modifier.Op != "by" {
+			return false
+		}
+		for _, label := range labels {
+			if !slices.Contains(e.Modifier.Args, label) {
+				return false
+			}
+		}
+		return true
+	case *metricsql.BinaryOpExpr:
+		return aggregatesByOperands(labels, e.Left, e.Right)
+	case *metricsql.FuncExpr:
+		return aggregatesByOperands(labels, e.Args...)
+	default:
+		return false
+	}
+}
+
+// aggregatesByOperands returns true, when all operands, which are not a
+// scalar, are aggregated by the provided labels and at least one operand is
+// not a scalar.
+func aggregatesByOperands(labels []string, operands ...metricsql.Expr) bool {
+	found := false
+	for _, operand := range operands {
+		switch operand.(type) {
+		case *metricsql.NumberExpr, *metricsql.StringExpr, *metricsql.DurationExpr:
+			continue
+		}
+		if !aggregatesBy(operand, labels) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validateGroupBy", func() {
+	It("Should accept queries, which aggregate by the labels", func() {
+		Expect(validateGroupBy([]string{"route"},
+			`sum by (route) (rate(http_requests_total{job="api"}[${window}]))`,
+			`sum(rate(http_requests_total{job="api",code=~"5.."}[${window}])) by (route, code)`,
+			`sum by (route) (rate(http_request_duration_seconds_count[${window}])) - sum by (route) (rate(http_request_duration_seconds_bucket{le="0.5"}[${window}]))`,
+			`clamp_min(sum by (route) (rate(http_requests_total[${window}])), 0)`,
+			`count by (route) (up == 0) * 60`,
+		)).To(Succeed())
+	})
+
+	It("Should reject queries, which don't aggregate by the labels", func() {
+		for _, query := range []string{
+			`sum(rate(http_requests_total{job="api"}[${window}]))`,
+			`sum without (instance) (rate(http_requests_total{job="api"}[${window}]))`,
+			`sum by (code) (rate(http_requests_total{job="api"}[${window}]))`,
+			`sum by (route) (rate(http_requests_total[${window}])) - sum(rate(http_requests_total{code=~"5.."}[${window}]))`,
+			`rate(http_requests_total{job="api"}[${window}])`,
+			`sum by (route) (rate(http_requests_total[${window}]`,
+		} {
+			Expect(validateGroupBy([]string{"route"}, query)).NotTo(Succeed(), query)
+		}
+	})
+
+	It("Should reject invalid and reserved labels", func() {
+		Expect(validateGroupBy([]string{"route-name"})).NotTo(Succeed())
+		Expect(validateGroupBy([]string{"window"})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// maintenanceHorizon is the time range for which the occurrences of
+	// scheduled maintenance windows are added to the rules.
+	maintenanceHorizon = 7 * 24 * time.Hour
+	// maintenanceAlertDelay is the time after the end of a maintenance window,
+	// for which the "SLOErrorBudgetBurn" alerts are still suppressed. The burn
+	// rates of the short windows are calculated from the user provided
+	// queries, so that they contain the errors of the maintenance window until
+	// the longest short window (6h) has passed.
+	maintenanceAlertDelay = 6 * time.Hour
+	// maintenanceRefreshInterval is the interval in which the rules are
+	// regenerated, when a ServiceLevelObjective contains a scheduled
+	// maintenance window. It must be smaller than the maintenanceHorizon.
+	maintenanceRefreshInterval = 24 * time.Hour
+	// maxMaintenanceOccurrences is the maximum number of occurrences of all
+	// maintenance windows within the maintenanceHorizon, to avoid huge
+	// expressions for schedules like "* * * * *".
+	maxMaintenanceOccurrences = 200
+)
+
+// timeRange is a single occurrence of a maintenance window.
+type timeRange struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// generateMaintenanceRanges returns the time ranges of all provided
+// maintenance windows, which are not over yet or ended within the
+// maintenanceAlertDelay, because the burn rate alerts are still suppressed for
+// them. For scheduled maintenance windows all occurrences, which are active or
+// start within the maintenanceHorizon are returned.
+func generateMaintenanceRanges(windows []ricobergerdev1alpha1.MaintenanceWindow, now time.Time) ([]timeRange, error) {
+	var ranges []timeRange
+
+	for _, window := range windows {
+		if window.Schedule == "" {
+			if window.Start == nil || window.End == nil || !window.End.After(window.Start.Time) {
+				return nil, fmt.Errorf("maintenance window %s requires a start and end or a schedule and duration", window.Name)
+			}
+			if window.End.Add(maintenanceAlertDelay).After(now) {
+				ranges = append(ranges, timeRange{Name: window.Name, Start: window.Start.Time, End: window.End.Time})
+			}
+			continue
+		}
+
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schedule of maintenance window %s: %w", window.Name, err)
+		}
+
+		duration, err := model.ParseDuration(window.Duration)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("maintenance window %s requires a valid duration", window.Name)
+		}
+
+		location, err := time.LoadLocation(window.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load timezone of maintenance window %s: %w", window.Name, err)
+		}
+
+		// Occurrences which started before now, but are not over yet or ended
+		// within the maintenanceAlertDelay, must also be added, so that we
+		// start with the search for occurrences one duration and the delay
+		// before now.
+		//
+		// Since the Next function returns the first match after the provided
+		// time, we subtract a second from the start. It returns the zero time,
+		// when the schedule never matches, e.g. for "0 0 30 2 *".
+		end := now.Add(maintenanceHorizon)
+		start := now.Add(-time.Duration(duration) - maintenanceAlertDelay).Truncate(time.Minute).Add(-time.Second)
+		for t := schedule.Next(start.In(location)); !t.IsZero() && t.Before(end); t = schedule.Next(t) {
+			occurrenceStart := t.In(now.Location())
+			if occurrenceEnd := occurrenceStart.Add(time.Duration(duration)); occurrenceEnd.Add(maintenanceAlertDelay).After(now) {
+				ranges = append(ranges, timeRange{Name: window.Name, Start: occurrenceStart, End: occurrenceEnd})
+			}
+			if len(ranges) > maxMaintenanceOccurrences {
+				return nil, fmt.Errorf("maintenance windows have more than %d occurrences", maxMaintenanceOccurrences)
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// nextMaintenanceChange returns the duration until the next start or end of
+// one of the provided time ranges. If no time range starts or ends after now,
+// 0 is returned.
+func nextMaintenanceChange(ranges []timeRange, now time.Time) time.Duration {
+	var next time.Time
+	for _, r := range ranges {
+		for _, t := range []time.Time{r.Start, r.End} {
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+
+	if next.IsZero() {
+		return 0
+	}
+	return next.Sub(now) + time.Second
+}
+
+// generateMaintenanceCondition returns an expression, which only returns a
+// result while one of the provided time ranges is active. The end of each time
+// range is extended by the provided delay. If no time ranges are provided an
+// empty string is returned.
+func generateMaintenanceCondition(ranges []timeRange, delay time.Duration) string {
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	conditions := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		conditions = append(conditions, fmt.Sprintf("vector(time()) >= %d < %d", r.Start.Unix(), r.End.Add(delay).Unix()))
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(conditions, " or "))
+}
+
+// validateMaintenanceWindows returns an error, when maintenance windows are
+// defined for a ServiceLevelObjective with a LogQL SLI. The "slo:total" and
+// "slo:errors_total" metrics of these SLOs are recorded by the Loki ruler and
+// LogQL has no "time()" function, so that the maintenance windows can not be
+// excluded from these metrics.
+func validateMaintenanceWindows(spec ricobergerdev1alpha1.ServiceLevelObjectiveSpec) error {
+	if len(spec.MaintenanceWindows) == 0 {
+		return nil
+	}
+
+	for _, slo := range spec.SLOs {
+		if strings.EqualFold(slo.SLI.Type, "logql") {
+			return fmt.Errorf("maintenance windows are not supported for slo %s with a LogQL SLI, use the silences maintenance mode instead", slo.Name)
+		}
+	}
+
+	return nil
+}
+
+// applyMaintenanceWindows excludes the provided maintenance windows from the
+// "slo:total" and "slo:errors_total" metrics, so that the maintenance windows
+// are not counted for the availability and the error budget. It also
+// suppresses the "SLOMetricAbsent" alerts during the maintenance windows and
+// adds a "slo:maintenance" recording rule to each group which contains the
+// "slo:total" metric, which is 1 during a maintenance window.
+//
+// The "SLOErrorBudgetBurn" alerts are suppressed until the
+// maintenanceAlertDelay after the end of each maintenance window, because the
+// burn rates of the short windows are not calculated from the masked
+// "slo:total" and "slo:errors_total" metrics.
+//
+// The conditions are used directly in all expressions instead of the recorded
+// "slo:maintenance" metric, because the rules are evaluated in different
+// groups.
+func applyMaintenanceWindows(groups []monitoringv1.RuleGroup, ranges []timeRange) []monitoringv1.RuleGroup {
+	if len(ranges) == 0 {
+		return groups
+	}
+
+	condition := generateMaintenanceCondition(ranges, 0)
+	alertCondition := generateMaintenanceCondition(ranges, maintenanceAlertDelay)
+
+	for i := range groups {
+		var rules []monitoringv1.Rule
+
+		for _, rule := range groups[i].Rules {
+			switch {
+			case rule.Record == "slo:total":
+				labels := make(map[string]string)
+				maps.Copy(labels, rule.Labels)
+
+				rules = append(rules, monitoringv1.Rule{
+					Record: "slo:maintenance",
+					Expr:   intstr.FromString(fmt.Sprintf("vector(1) and on() %s", condition)),
+					Labels: labels,
+				})
+				fallthrough
+			case rule.Record == "slo:errors_total", rule.Alert == "SLOMetricAbsent":
+				rule.Expr = intstr.FromString(fmt.Sprintf("(%s) unless on() %s", rule.Expr.String(), condition))
+			case rule.Alert == "SLOErrorBudgetBurn":
+				rule.Expr = intstr.FromString(fmt.Sprintf("(%s) unless on() %s", rule.Expr.String(), alertCondition))
+			}
+
+			rules = append(rules, rule)
+		}
+
+		groups[i].Rules = rules
+	}
+
+	return groups
+}
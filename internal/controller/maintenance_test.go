@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("generateMaintenanceRanges", func() {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	It("Should return the one-off maintenance windows, which are not over", func() {
+		ranges, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{
+			{
+				Name:  "past",
+				Start: &metav1.Time{Time: now.Add(-8 * time.Hour)},
+				End:   &metav1.Time{Time: now.Add(-7 * time.Hour)},
+			},
+			{
+				Name:  "upgrade",
+				Start: &metav1.Time{Time: now.Add(1 * time.Hour)},
+				End:   &metav1.Time{Time: now.Add(2 * time.Hour)},
+			},
+		}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ranges).To(Equal([]timeRange{{Name: "upgrade", Start: now.Add(1 * time.Hour), End: now.Add(2 * time.Hour)}}))
+		Expect(generateMaintenanceCondition(ranges, 0)).To(Equal("(vector(time()) >= 1760533200 < 1760536800)"))
+		Expect(generateMaintenanceCondition(ranges, maintenanceAlertDelay)).To(Equal("(vector(time()) >= 1760533200 < 1760558400)"))
+	})
+
+	It("Should return the maintenance windows, which ended within the alert delay", func() {
+		ranges, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{
+			{
+				Name:  "upgrade",
+				Start: &metav1.Time{Time: now.Add(-2 * time.Hour)},
+				End:   &metav1.Time{Time: now.Add(-1 * time.Hour)},
+			},
+			{
+				Name:     "nightly",
+				Schedule: "0 8 * * *",
+				Duration: "1h",
+			},
+		}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ranges[0]).To(Equal(timeRange{Name: "upgrade", Start: now.Add(-2 * time.Hour), End: now.Add(-1 * time.Hour)}))
+		Expect(ranges[1]).To(Equal(timeRange{Name: "nightly", Start: now.Add(-4 * time.Hour), End: now.Add(-3 * time.Hour)}))
+		Expect(ranges).To(HaveLen(9))
+	})
+
+	It("Should return the occurrences of the scheduled maintenance windows", func() {
+		ranges, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{
+			{
+				Name:     "nightly",
+				Schedule: "0 13 * * *",
+				Duration: "2h",
+				Timezone: "Europe/Berlin",
+			},
+		}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ranges).To(HaveLen(8))
+
+		By("Including the active occurrence")
+		Expect(ranges[0]).To(Equal(timeRange{Name: "nightly", Start: time.Date(2025, 10, 15, 11, 0, 0, 0, time.UTC), End: time.Date(2025, 10, 15, 13, 0, 0, 0, time.UTC)}))
+
+		By("Handling the change from daylight saving time")
+		Expect(ranges[7].Start).To(Equal(time.Date(2025, 10, 22, 11, 0, 0, 0, time.UTC)))
+	})
+
+	It("Should support the cron syntax for the schedule", func() {
+		for _, tc := range []struct {
+			schedule string
+			start    time.Time
+		}{
+			{schedule: "0 2 * * 0", start: time.Date(2025, 10, 19, 2, 0, 0, 0, time.UTC)},
+			{schedule: "0 2 * * SUN", start: time.Date(2025, 10, 19, 2, 0, 0, 0, time.UTC)},
+			{schedule: "0 0 * * 1-5", start: time.Date(2025, 10, 16, 0, 0, 0, 0, time.UTC)},
+			{schedule: "*/15 8-18/2 1,15 * *", start: time.Date(2025, 10, 15, 8, 0, 0, 0, time.UTC)},
+			{schedule: "0 0 20 * *", start: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+			// If the day of month and the day of week are restricted, a time
+			// matches when one of them matches.
+			{schedule: "0 0 1 * 1", start: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+			{schedule: "0 0 15 * 1", start: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+			{schedule: "0 0 15,20 * *", start: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+			// A step like "*/2" also restricts the field, so that the day of
+			// month (every odd day) or the day of week must match.
+			{schedule: "0 0 */2 * 1", start: time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC)},
+			// If one of them is "*", both must match.
+			{schedule: "0 0 * 10 1", start: time.Date(2025, 10, 20, 0, 0, 0, 0, time.UTC)},
+			{schedule: "@daily", start: time.Date(2025, 10, 16, 0, 0, 0, 0, time.UTC)},
+		} {
+			ranges, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{
+				{Name: "maintenance", Schedule: tc.schedule, Duration: "1m"},
+			}, now)
+			Expect(err).NotTo(HaveOccurred(), tc.schedule)
+			Expect(ranges).NotTo(BeEmpty(), tc.schedule)
+			Expect(ranges[0].Start).To(Equal(tc.start), tc.schedule)
+		}
+
+		By("Ignoring schedules, which never match")
+		ranges, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{
+			{Name: "maintenance", Schedule: "0 0 30 2 *", Duration: "1m"},
+		}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ranges).To(BeEmpty())
+	})
+
+	It("Should fail for invalid maintenance windows", func() {
+		for _, window := range []ricobergerdev1alpha1.MaintenanceWindow{
+			{Name: "missing-end", Start: &metav1.Time{Time: now}},
+			{Name: "missing-duration", Schedule: "0 2 * * *"},
+			{Name: "invalid-timezone", Schedule: "0 2 * * *", Duration: "1h", Timezone: "Europe/Invalid"},
+			{Name: "too-many-occurrences", Schedule: "* * * * *", Duration: "1m"},
+			{Name: "invalid-schedule-fields", Schedule: "0 2 * *", Duration: "1h"},
+			{Name: "invalid-schedule-value", Schedule: "60 2 * * *", Duration: "1h"},
+			{Name: "invalid-schedule-step", Schedule: "*/0 * * * *", Duration: "1h"},
+			{Name: "invalid-schedule-range", Schedule: "5-1 * * * *", Duration: "1h"},
+			{Name: "invalid-schedule-day-of-week", Schedule: "0 2 * * 8", Duration: "1h"},
+		} {
+			_, err := generateMaintenanceRanges([]ricobergerdev1alpha1.MaintenanceWindow{window}, now)
+			Expect(err).To(HaveOccurred(), window.Name)
+		}
+	})
+})
+
+var _ = Describe("applyMaintenanceWindows", func() {
+	It("Should exclude the maintenance windows from the rules", func() {
+		groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI: ricobergerdev1alpha1.SLI{
+				TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+				ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+			},
+		}, map[string]string{"name": "test", "namespace": "default"}, promQL)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(applyMaintenanceWindows(groups, nil)).To(Equal(groups))
+
+		condition := "(vector(time()) >= 1760533200 < 1760536800)"
+		groups = applyMaintenanceWindows(groups, []timeRange{{Name: "upgrade", Start: time.Unix(1760533200, 0), End: time.Unix(1760536800, 0)}})
+
+		exprs := make(map[string]string)
+		for _, group := range groups {
+			for _, rule := range group.Rules {
+				exprs[rule.Record+rule.Alert+rule.Labels["window"]+rule.Labels["severity"]] = rule.Expr.String()
+			}
+		}
+
+		Expect(groups[0].Rules[2].Record).To(Equal("slo:maintenance"))
+		Expect(groups[0].Rules[2].Labels).To(HaveKeyWithValue("id", "test-default-availability"))
+		Expect(exprs).To(HaveKeyWithValue("slo:maintenance", "vector(1) and on() "+condition))
+		Expect(exprs).To(HaveKeyWithValue("slo:total", `(sum(rate(http_requests_total{job="api"}[2m]))) unless on() `+condition))
+		Expect(exprs).To(HaveKeyWithValue("slo:errors_total", `((sum(rate(http_requests_total{job="api",code=~"5.."}[2m]))) or vector(0)) unless on() `+condition))
+		Expect(exprs).To(HaveKeyWithValue("SLOMetricAbsentcritical", `(absent(sum(rate(http_requests_total{job="api"}[2m]))) == 1) unless on() `+condition))
+
+		By("Suppressing the burn rate alerts until the short windows are free of maintenance errors")
+		Expect(groups[1].Rules[7].Expr.String()).To(Equal(`(slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.99))) unless on() (vector(time()) >= 1760533200 < 1760558400)`))
+		Expect(exprs).To(HaveKeyWithValue("slo:burnrate5m", `(sum(rate(http_requests_total{job="api",code=~"5.."}[5m]))) / (sum(rate(http_requests_total{job="api"}[5m])))`))
+	})
+})
+
+var _ = Describe("validateMaintenanceWindows", func() {
+	It("Should reject maintenance windows for LogQL SLIs", func() {
+		spec := ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+			SLOs: []ricobergerdev1alpha1.SLO{
+				{Name: "availability", SLI: ricobergerdev1alpha1.SLI{Type: "PromQL"}},
+				{Name: "logs", SLI: ricobergerdev1alpha1.SLI{Type: "LogQL"}},
+			},
+		}
+		Expect(validateMaintenanceWindows(spec)).To(Succeed())
+
+		spec.MaintenanceWindows = []ricobergerdev1alpha1.MaintenanceWindow{{Name: "upgrade", Schedule: "0 20 * * 6", Duration: "2h"}}
+		Expect(validateMaintenanceWindows(spec)).To(MatchError(ContainSubstring("slo logs")))
+
+		spec.SLOs = spec.SLOs[:1]
+		Expect(validateMaintenanceWindows(spec)).To(Succeed())
+	})
+})
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	"k8s.io/utils/ptr"
+)
+
+// sliPresets are the built-in SLIs for common exporters, which can be selected
+// via the "preset" field of a SLI. The presets are defined like a
+// ServiceLevelIndicator, so that the parameters are rendered in the same way.
+// All parameters are used as values of label matchers and are quoted when the
+// preset is rendered, see renderSLIPreset.
+// Most presets are counting the events per second via "rate". The presets in
+// ratioOnlySLIPresets are based on gauges instead, see validateSLIPreset.
+var sliPresets = map[string]ricobergerdev1alpha1.ServiceLevelIndicatorSpec{
+	// ingress-nginx: The availability of all requests for a host, where the
+	// requests with a 5xx status code are counted as errors.
+	"ingress-nginx": {
+		TotalQuery: `sum(rate(nginx_ingress_controller_requests{host=${host}}[${window}]))`,
+		ErrorQuery: `sum(rate(nginx_ingress_controller_requests{host=${host},status=~${errorStatus}}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "host"},
+			{Name: "errorStatus", Default: ptr.To("5..")},
+		},
+	},
+	// istio: The availability of all requests for a service, as reported by
+	// the Envoy sidecar of the destination workload.
+	"istio": {
+		TotalQuery: `sum(rate(istio_requests_total{reporter="destination",destination_service_namespace=${namespace},destination_service_name=${service}}[${window}]))`,
+		ErrorQuery: `sum(rate(istio_requests_total{reporter="destination",destination_service_namespace=${namespace},destination_service_name=${service},response_code=~${errorStatus}}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "namespace"},
+			{Name: "service"},
+			{Name: "errorStatus", Default: ptr.To("5..")},
+		},
+	},
+	// envoy: The availability of all requests to an upstream cluster of Envoy,
+	// where the requests are counted by the class of the status code.
+	"envoy": {
+		TotalQuery: `sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name=${cluster}}[${window}]))`,
+		ErrorQuery: `sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name=${cluster},envoy_response_code_class=~${errorStatusClass}}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "cluster"},
+			{Name: "errorStatusClass", Default: ptr.To("5")},
+		},
+	},
+	// grpc: The availability of all handled RPCs of a gRPC service, as
+	// reported by the go-grpc-prometheus middleware. The codes, which are
+	// caused by the server, are counted as errors.
+	"grpc": {
+		TotalQuery: `sum(rate(grpc_server_handled_total{grpc_service=${service},grpc_method=~${method}}[${window}]))`,
+		ErrorQuery: `sum(rate(grpc_server_handled_total{grpc_service=${service},grpc_method=~${method},grpc_code=~${errorCodes}}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "service"},
+			{Name: "method", Default: ptr.To(".+")},
+			{Name: "errorCodes", Default: ptr.To("Unknown|DeadlineExceeded|Internal|Unavailable|DataLoss")},
+		},
+	},
+	// traefik: The availability of all requests for a Traefik service, where
+	// the requests with a 5xx status code are counted as errors.
+	"traefik": {
+		TotalQuery: `sum(rate(traefik_service_requests_total{service=${service}}[${window}]))`,
+		ErrorQuery: `sum(rate(traefik_service_requests_total{service=${service},code=~${errorStatus}}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "service"},
+			{Name: "errorStatus", Default: ptr.To("5..")},
+		},
+	},
+	// kube-state-metrics: The availability of the replicas of a Deployment,
+	// where the desired replicas, which are not available, are counted as
+	// errors.
+	"kube-state-metrics": {
+		TotalQuery: `sum(avg_over_time(kube_deployment_spec_replicas{namespace=${namespace},deployment=${deployment}}[${window}]))`,
+		ErrorQuery: `clamp_min(sum(avg_over_time(kube_deployment_spec_replicas{namespace=${namespace},deployment=${deployment}}[${window}])) - sum(avg_over_time(kube_deployment_status_replicas_available{namespace=${namespace},deployment=${deployment}}[${window}])), 0)`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "namespace"},
+			{Name: "deployment"},
+		},
+	},
+}
+
+// ratioOnlySLIPresets are the presets, which are based on gauges and not on
+// counters. Their queries are averaging a gauge via "avg_over_time", so that
+// they only return a ratio and not the number of events per second.
+var ratioOnlySLIPresets = []string{"kube-state-metrics"}
+
+// isRatioOnlySLIPreset returns true, when the provided preset is one of the
+// ratioOnlySLIPresets.
+func isRatioOnlySLIPreset(preset *ricobergerdev1alpha1.SLIPreset) bool {
+	return preset != nil && slices.Contains(ratioOnlySLIPresets, strings.ToLower(preset.Name))
+}
+
+// validateSLIPreset validates the alerting options of the provided SLO and of
+// all its objectives, when the SLO is using a preset. The minimum events,
+// traffic-scaled thresholds and dynamic burn rates are based on the number of
+// events, so that they are not supported for the presets in
+// ratioOnlySLIPresets.
+func validateSLIPreset(slo ricobergerdev1alpha1.SLO) error {
+	if !isRatioOnlySLIPreset(slo.SLI.Preset) {
+		return nil
+	}
+
+	alertings := []ricobergerdev1alpha1.Alerting{slo.Alerting}
+	for _, objective := range slo.Objectives {
+		alertings = append(alertings, objective.Alerting)
+	}
+
+	for _, alerting := range alertings {
+		if alerting.MinimumEvents > 0 || alerting.TrafficScaledThresholds || strings.EqualFold(alerting.BurnRateType, "dynamic") {
+			return fmt.Errorf("minimum events, traffic-scaled thresholds and dynamic burn rates are not supported for the %s preset", slo.SLI.Preset.Name)
+		}
+	}
+
+	return nil
+}
+
+// renderSLIPreset returns the SLI for the provided preset, where the
+// placeholders of the parameters are replaced by the provided values or the
+// default values of the preset. The values are quoted via strconv.Quote,
+// which uses the same escaping as the strings in PromQL, so that a value can
+// not break out of the label matcher.
+func renderSLIPreset(preset ricobergerdev1alpha1.SLIPreset) (ricobergerdev1alpha1.SLI, error) {
+	spec, ok := sliPresets[strings.ToLower(preset.Name)]
+	if !ok {
+		return ricobergerdev1alpha1.SLI{}, fmt.Errorf("unknown preset %s", preset.Name)
+	}
+
+	parameters := make([]ricobergerdev1alpha1.Parameter, 0, len(spec.Parameters))
+	for _, parameter := range spec.Parameters {
+		if parameter.Default != nil {
+			parameter.Default = ptr.To(strconv.Quote(*parameter.Default))
+		}
+		parameters = append(parameters, parameter)
+	}
+	spec.Parameters = parameters
+
+	values := make(map[string]string, len(preset.Parameters))
+	for name, value := range preset.Parameters {
+		values[name] = strconv.Quote(value)
+	}
+
+	return renderServiceLevelIndicator(spec, values)
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("renderSLIPreset", func() {
+	labels := map[string]string{
+		"name":      "test",
+		"namespace": "default",
+	}
+
+	for _, tc := range []struct {
+		preset      ricobergerdev1alpha1.SLIPreset
+		total       string
+		errorsTotal string
+		burnrate    string
+	}{
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "ingress-nginx", Parameters: map[string]string{"host": "example.com"}},
+			total:       `sum(rate(nginx_ingress_controller_requests{host="example.com"}[2m]))`,
+			errorsTotal: `(sum(rate(nginx_ingress_controller_requests{host="example.com",status=~"5.."}[2m]))) or vector(0)`,
+			burnrate:    `(sum(rate(nginx_ingress_controller_requests{host="example.com",status=~"5.."}[5m]))) / (sum(rate(nginx_ingress_controller_requests{host="example.com"}[5m])))`,
+		},
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "istio", Parameters: map[string]string{"namespace": "shop", "service": "checkout", "errorStatus": "5..|429"}},
+			total:       `sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="shop",destination_service_name="checkout"}[2m]))`,
+			errorsTotal: `(sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="shop",destination_service_name="checkout",response_code=~"5..|429"}[2m]))) or vector(0)`,
+			burnrate:    `(sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="shop",destination_service_name="checkout",response_code=~"5..|429"}[5m]))) / (sum(rate(istio_requests_total{reporter="destination",destination_service_namespace="shop",destination_service_name="checkout"}[5m])))`,
+		},
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "envoy", Parameters: map[string]string{"cluster": "backend"}},
+			total:       `sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name="backend"}[2m]))`,
+			errorsTotal: `(sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name="backend",envoy_response_code_class=~"5"}[2m]))) or vector(0)`,
+			burnrate:    `(sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name="backend",envoy_response_code_class=~"5"}[5m]))) / (sum(rate(envoy_cluster_upstream_rq_xx{envoy_cluster_name="backend"}[5m])))`,
+		},
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "gRPC", Parameters: map[string]string{"service": "shop.Checkout", "method": "Pay"}},
+			total:       `sum(rate(grpc_server_handled_total{grpc_service="shop.Checkout",grpc_method=~"Pay"}[2m]))`,
+			errorsTotal: `(sum(rate(grpc_server_handled_total{grpc_service="shop.Checkout",grpc_method=~"Pay",grpc_code=~"Unknown|DeadlineExceeded|Internal|Unavailable|DataLoss"}[2m]))) or vector(0)`,
+			burnrate:    `(sum(rate(grpc_server_handled_total{grpc_service="shop.Checkout",grpc_method=~"Pay",grpc_code=~"Unknown|DeadlineExceeded|Internal|Unavailable|DataLoss"}[5m]))) / (sum(rate(grpc_server_handled_total{grpc_service="shop.Checkout",grpc_method=~"Pay"}[5m])))`,
+		},
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "traefik", Parameters: map[string]string{"service": "shop-checkout-80@kubernetes"}},
+			total:       `sum(rate(traefik_service_requests_total{service="shop-checkout-80@kubernetes"}[2m]))`,
+			errorsTotal: `(sum(rate(traefik_service_requests_total{service="shop-checkout-80@kubernetes",code=~"5.."}[2m]))) or vector(0)`,
+			burnrate:    `(sum(rate(traefik_service_requests_total{service="shop-checkout-80@kubernetes",code=~"5.."}[5m]))) / (sum(rate(traefik_service_requests_total{service="shop-checkout-80@kubernetes"}[5m])))`,
+		},
+		{
+			preset:      ricobergerdev1alpha1.SLIPreset{Name: "kube-state-metrics", Parameters: map[string]string{"namespace": "shop", "deployment": "checkout"}},
+			total:       `sum(avg_over_time(kube_deployment_spec_replicas{namespace="shop",deployment="checkout"}[2m]))`,
+			errorsTotal: `(clamp_min(sum(avg_over_time(kube_deployment_spec_replicas{namespace="shop",deployment="checkout"}[2m])) - sum(avg_over_time(kube_deployment_status_replicas_available{namespace="shop",deployment="checkout"}[2m])), 0)) or vector(0)`,
+			burnrate:    `(clamp_min(sum(avg_over_time(kube_deployment_spec_replicas{namespace="shop",deployment="checkout"}[5m])) - sum(avg_over_time(kube_deployment_status_replicas_available{namespace="shop",deployment="checkout"}[5m])), 0)) / (sum(avg_over_time(kube_deployment_spec_replicas{namespace="shop",deployment="checkout"}[5m])))`,
+		},
+	} {
+		It("Should generate the rules for the "+tc.preset.Name+" preset", func() {
+			sli, err := renderSLIPreset(tc.preset)
+			Expect(err).NotTo(HaveOccurred())
+
+			groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{Name: "availability", Objective: "99", SLI: sli}, labels, promQL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups[0].Rules[2].Record).To(Equal("slo:total"))
+			Expect(groups[0].Rules[2].Expr.String()).To(Equal(tc.total))
+			Expect(groups[0].Rules[3].Record).To(Equal("slo:errors_total"))
+			Expect(groups[0].Rules[3].Expr.String()).To(Equal(tc.errorsTotal))
+			Expect(groups[1].Rules[0].Record).To(Equal("slo:burnrate"))
+			Expect(groups[1].Rules[0].Expr.String()).To(Equal(tc.burnrate))
+		})
+	}
+
+	It("Should fail for unknown presets and missing parameters", func() {
+		_, err := renderSLIPreset(ricobergerdev1alpha1.SLIPreset{Name: "haproxy"})
+		Expect(err).To(HaveOccurred())
+
+		_, err = renderSLIPreset(ricobergerdev1alpha1.SLIPreset{Name: "ingress-nginx"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should resolve the presets of a ServiceLevelObjective", func() {
+		controllerReconciler := &ServiceLevelObjectiveReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		slo := &ricobergerdev1alpha1.ServiceLevelObjective{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+				SLOs: []ricobergerdev1alpha1.SLO{
+					{
+						Name:      "availability",
+						Objective: "99",
+						SLI: ricobergerdev1alpha1.SLI{
+							Preset: &ricobergerdev1alpha1.SLIPreset{Name: "traefik", Parameters: map[string]string{"service": "api"}},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(controllerReconciler.resolveServiceLevelIndicators(context.Background(), slo)).To(Succeed())
+		Expect(slo.Spec.SLOs[0].SLI.TotalQuery).To(Equal(`sum(rate(traefik_service_requests_total{service="api"}[${window}]))`))
+
+		By("Failing when the preset is used together with queries")
+		slo.Spec.SLOs[0].SLI.Preset = &ricobergerdev1alpha1.SLIPreset{Name: "traefik", Parameters: map[string]string{"service": "api"}}
+		Expect(controllerReconciler.resolveServiceLevelIndicators(context.Background(), slo)).NotTo(Succeed())
+	})
+
+	It("Should quote the parameter values", func() {
+		sli, err := renderSLIPreset(ricobergerdev1alpha1.SLIPreset{Name: "traefik", Parameters: map[string]string{"service": `api"} or vector(1) or up{service="`}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sli.TotalQuery).To(Equal(`sum(rate(traefik_service_requests_total{service="api\"} or vector(1) or up{service=\""}[${window}]))`))
+
+		sli, err = renderSLIPreset(ricobergerdev1alpha1.SLIPreset{Name: "traefik", Parameters: map[string]string{"service": "api", "errorStatus": `5\d\d`}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sli.ErrorQuery).To(Equal(`sum(rate(traefik_service_requests_total{service="api",code=~"5\\d\\d"}[${window}]))`))
+	})
+
+	It("Should reject traffic based alerting options for the kube-state-metrics preset", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "replicas",
+			Objective: "99",
+			SLI: ricobergerdev1alpha1.SLI{
+				Preset: &ricobergerdev1alpha1.SLIPreset{Name: "kube-state-metrics", Parameters: map[string]string{"namespace": "default", "deployment": "api"}},
+			},
+		}
+		Expect(validateSLIPreset(slo)).To(Succeed())
+
+		for _, alerting := range []ricobergerdev1alpha1.Alerting{
+			{MinimumEvents: 10},
+			{TrafficScaledThresholds: true},
+			{BurnRateType: "dynamic"},
+		} {
+			invalid := *slo.DeepCopy()
+			invalid.Alerting = alerting
+			Expect(validateSLIPreset(invalid)).NotTo(Succeed())
+
+			invalid = *slo.DeepCopy()
+			invalid.Objectives = []ricobergerdev1alpha1.Objective{{Name: "internal", Objective: "95", Alerting: alerting}}
+			Expect(validateSLIPreset(invalid)).NotTo(Succeed())
+		}
+
+		slo.SLI.Preset = &ricobergerdev1alpha1.SLIPreset{Name: "traefik"}
+		slo.Alerting.BurnRateType = "dynamic"
+		Expect(validateSLIPreset(slo)).To(Succeed())
+	})
+
+	It("Should not record the remaining events for the kube-state-metrics preset", func() {
+		preset := &ricobergerdev1alpha1.SLIPreset{Name: "kube-state-metrics", Parameters: map[string]string{"namespace": "default", "deployment": "api"}}
+		sli, err := renderSLIPreset(*preset)
+		Expect(err).NotTo(HaveOccurred())
+		sli.Preset = preset
+
+		groups, err := generateErrorBudgetRuleGroups(ricobergerdev1alpha1.SLO{Name: "replicas", Objective: "99", SLI: sli}, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).NotTo(BeEmpty())
+		for _, group := range groups {
+			for _, rule := range group.Rules {
+				Expect(rule.Record).NotTo(Equal("slo:error_budget_remaining_events"))
+			}
+		}
+	})
+})
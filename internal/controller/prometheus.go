@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// prometheusQueryResponse is the response of the "/api/v1/query" endpoint of
+// the Prometheus HTTP API for a query, which returns an instant vector.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []any             `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// queryPrometheus runs the provided query against the Prometheus HTTP API,
+// which is configured via the "SLO_OPERATOR_PROMETHEUS_URL" environment
+// variable. It returns the values of the result by the "id" label.
+func queryPrometheus(ctx context.Context, query string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/v1/query?query=%s", strings.TrimSuffix(sloOperatorPrometheusURL, "/"), url.QueryEscape(query)), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var queryResponse prometheusQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&queryResponse); err != nil {
+		return nil, err
+	}
+	if queryResponse.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", queryResponse.Error)
+	}
+
+	values := make(map[string]float64)
+	for _, result := range queryResponse.Data.Result {
+		if len(result.Value) != 2 {
+			continue
+		}
+		value, ok := result.Value[1].(string)
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		values[result.Metric["id"]] = f
+	}
+
+	return values, nil
+}
+
+// updateSLOStatus queries the current availability, remaining error budget and
+// the forecasted exhaustion time of the error budget for all SLOs of the
+// ServiceLevelObjective from Prometheus and sets them in the existing status
+// entries of the SLOs. The status is only updated in memory and must be
+// persisted by the caller.
+//
+// When a SLO is grouped via "groupBy", the recorded metrics contain one series
+// per group. The status always shows the worst group, i.e. the lowest
+// availability, the lowest remaining error budget and the earliest forecasted
+// exhaustion time, so that a single failing group is not hidden.
+func updateSLOStatus(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) error {
+	// The series of additional objectives are ignored, because the status
+	// only contains the objective of the SLO itself.
+	selector := fmt.Sprintf(`{namespace="%s", name="%s", objective=""}`, slo.Namespace, slo.Name)
+
+	availability, err := queryPrometheus(ctx, "min by (id) (slo:availability"+selector+")")
+	if err != nil {
+		return fmt.Errorf("failed to query availability: %w", err)
+	}
+	remaining, err := queryPrometheus(ctx, "min by (id) (slo:error_budget_remaining"+selector+")")
+	if err != nil {
+		return fmt.Errorf("failed to query remaining error budget: %w", err)
+	}
+	exhaustion, err := queryPrometheus(ctx, "min by (id) (slo:error_budget_exhaustion_timestamp"+selector+")")
+	if err != nil {
+		return fmt.Errorf("failed to query error budget exhaustion timestamp: %w", err)
+	}
+
+	labels := map[string]string{"name": slo.Name, "namespace": slo.Namespace}
+
+	for i := range slo.Status.SLOs {
+		status := &slo.Status.SLOs[i]
+		id := generateSLOID(labels, status.Name)
+
+		if value, ok := availability[id]; ok {
+			status.Availability = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		if value, ok := remaining[id]; ok {
+			status.ErrorBudgetRemaining = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		if value, ok := exhaustion[id]; ok && !math.IsInf(value, 0) && !math.IsNaN(value) {
+			status.ErrorBudgetExhaustionTime = &metav1.Time{Time: time.Unix(int64(value), 0).UTC()}
+		}
+	}
+
+	return nil
+}
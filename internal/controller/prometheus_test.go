@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newFakePrometheus returns a local stand-in for the query API of Prometheus,
+// which returns the provided values for a query by the name of the queried
+// metric. Queries, which are not aggregated by the id of the SLO, are rejected,
+// because they would return one series per group for grouped SLOs.
+func newFakePrometheus(values map[string]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query, ok := strings.CutPrefix(r.URL.Query().Get("query"), "min by (id) (")
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		metric := query[:strings.Index(query, "{")]
+
+		var result []map[string]any
+		for id, value := range values[metric] {
+			result = append(result, map[string]any{
+				"metric": map[string]string{"__name__": metric, "id": id},
+				"value":  []any{float64(time.Now().Unix()), value},
+			})
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"status": "success",
+			"data": map[string]any{
+				"resultType": "vector",
+				"result":     result,
+			},
+		})
+	}))
+}
+
+var _ = Describe("ServiceLevelObjective Controller (Status)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-status",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-status",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+						{
+							Name:      "latency",
+							Objective: "95",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}])) - sum(rate(http_request_duration_seconds_bucket{job="api",le="0.5"}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			})
+		})
+
+		It("Should set the live status of the SLOs", func() {
+			server := newFakePrometheus(map[string]map[string]string{
+				"slo:availability": {
+					"test-status-default-availability": "0.995",
+					"test-status-default-latency":      "0.97",
+				},
+				"slo:error_budget_remaining": {
+					"test-status-default-availability": "0.5",
+					"test-status-default-latency":      "0.4",
+				},
+				"slo:error_budget_exhaustion_timestamp": {
+					"test-status-default-availability": "1767225600",
+				},
+			})
+			DeferCleanup(server.Close)
+
+			sloOperatorPrometheusURL = server.URL
+			DeferCleanup(func() {
+				sloOperatorPrometheusURL = ""
+			})
+
+			By("Reconciling the created resource")
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(statusRefreshInterval))
+
+			By("Checking the status of the resource")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.SLOs).To(HaveLen(2))
+			Expect(resource.Status.SLOs[0].Name).To(Equal("availability"))
+			Expect(resource.Status.SLOs[0].Availability).To(Equal("0.995"))
+			Expect(resource.Status.SLOs[0].ErrorBudgetRemaining).To(Equal("0.5"))
+			Expect(resource.Status.SLOs[0].ErrorBudgetExhaustionTime).NotTo(BeNil())
+			Expect(resource.Status.SLOs[0].ErrorBudgetExhaustionTime.Unix()).To(Equal(int64(1767225600)))
+			Expect(resource.Status.SLOs[1].Name).To(Equal("latency"))
+			Expect(resource.Status.SLOs[1].Availability).To(Equal("0.97"))
+			Expect(resource.Status.SLOs[1].ErrorBudgetRemaining).To(Equal("0.4"))
+			Expect(resource.Status.SLOs[1].ErrorBudgetExhaustionTime).To(BeNil())
+		})
+
+		It("Should not fail when Prometheus is not reachable", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			DeferCleanup(server.Close)
+
+			sloOperatorPrometheusURL = server.URL
+			DeferCleanup(func() {
+				sloOperatorPrometheusURL = ""
+			})
+
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(statusRefreshInterval))
+		})
+	})
+})
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// rulerClient is a client for the ruler API of Grafana Mimir / Cortex. The
+// client can be used to create, update and delete rule groups for a tenant.
+//
+// See https://grafana.com/docs/mimir/latest/references/http-api/#ruler
+type rulerClient struct {
+	// address is the address of the ruler API, e.g. "http://mimir:8080".
+	address string
+	// prefix is the path prefix of the rules endpoints, e.g.
+	// "/prometheus/config/v1/rules" for Grafana Mimir.
+	prefix string
+}
+
+// setRuleGroup creates or updates the provided rule group in the namespace of
+// the tenant. The partial response strategy is a field of the Thanos Ruler,
+// which is rejected by the ruler APIs, so that it is removed from the group.
+func (c *rulerClient) setRuleGroup(ctx context.Context, tenant, namespace string, group monitoringv1.RuleGroup) error {
+	group.PartialResponseStrategy = ""
+
+	body, err := yaml.Marshal(group)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, http.MethodPost, tenant, fmt.Sprintf("%s/%s", c.prefix, url.PathEscape(namespace)), body)
+}
+
+// deleteRuleGroup deletes the rule group with the provided name from the
+// namespace of the tenant. If the rule group doesn't exist, no error is
+// returned.
+func (c *rulerClient) deleteRuleGroup(ctx context.Context, tenant, namespace, group string) error {
+	return c.do(ctx, http.MethodDelete, tenant, fmt.Sprintf("%s/%s/%s", c.prefix, url.PathEscape(namespace), url.PathEscape(group)), nil)
+}
+
+func (c *rulerClient) do(ctx context.Context, method, tenant, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", strings.TrimSuffix(c.address, "/"), path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Scope-OrgID", tenant)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodDelete {
+		return nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d for %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// syncRuler syncs the provided rule groups to the ruler API, so that the
+// tenant / namespace contains exactly the provided groups. The current status
+// contains the groups, which were created during the last sync. These groups
+// are deleted when they are not part of the provided groups anymore or when
+// the tenant / namespace was changed.
+//
+// The returned status always contains all groups, which are owned by the
+// ServiceLevelObjective after the sync, also when an error is returned, so
+// that the groups can be cleaned up in the next reconciliation.
+func syncRuler(ctx context.Context, client *rulerClient, current *ricobergerdev1alpha1.RulerStatus, tenant, namespace string, groups []monitoringv1.RuleGroup) (*ricobergerdev1alpha1.RulerStatus, error) {
+	reqLogger := log.FromContext(ctx)
+
+	var owned []string
+
+	if current != nil {
+		if current.Tenant != tenant || current.Namespace != namespace {
+			for _, group := range current.Groups {
+				reqLogger.Info("Deleting rule group from previous tenant / namespace.", "tenant", current.Tenant, "namespace", current.Namespace, "group", group)
+				if err := client.deleteRuleGroup(ctx, current.Tenant, current.Namespace, group); err != nil {
+					return current, fmt.Errorf("failed to delete rule group %s: %w", group, err)
+				}
+			}
+		} else {
+			owned = slices.Clone(current.Groups)
+		}
+	}
+
+	status := &ricobergerdev1alpha1.RulerStatus{
+		Tenant:    tenant,
+		Namespace: namespace,
+	}
+
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+
+		if err := client.setRuleGroup(ctx, tenant, namespace, group); err != nil {
+			status.Groups = owned
+			return status, fmt.Errorf("failed to set rule group %s: %w", group.Name, err)
+		}
+		if !slices.Contains(owned, group.Name) {
+			owned = append(owned, group.Name)
+		}
+	}
+
+	for _, group := range slices.Clone(owned) {
+		if slices.Contains(names, group) {
+			continue
+		}
+
+		reqLogger.Info("Deleting stale rule group.", "tenant", tenant, "namespace", namespace, "group", group)
+		if err := client.deleteRuleGroup(ctx, tenant, namespace, group); err != nil {
+			status.Groups = owned
+			return status, fmt.Errorf("failed to delete rule group %s: %w", group, err)
+		}
+		owned = slices.DeleteFunc(owned, func(g string) bool { return g == group })
+	}
+
+	status.Groups = owned
+	return status, nil
+}
+
+// deleteRuler deletes all rule groups from the ruler API, which are contained
+// in the provided status.
+func deleteRuler(ctx context.Context, client *rulerClient, current *ricobergerdev1alpha1.RulerStatus) error {
+	if current == nil {
+		return nil
+	}
+
+	for _, group := range current.Groups {
+		if err := client.deleteRuleGroup(ctx, current.Tenant, current.Namespace, group); err != nil {
+			return fmt.Errorf("failed to delete rule group %s: %w", group, err)
+		}
+	}
+
+	return nil
+}
+
+// updateRulerStatus persists the status of the ServiceLevelObjective directly
+// after the rule groups were synced to a ruler API, when the synced groups
+// have changed. The groups can only be cleaned up, when they are tracked in
+// the status, so that a failed update is returned to retry the
+// reconciliation. The provided error of the sync is returned together with
+// the error of the update.
+func (r *ServiceLevelObjectiveReconciler) updateRulerStatus(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, changed bool, syncErr error) error {
+	if !changed {
+		return syncErr
+	}
+
+	if err := r.Status().Update(ctx, slo); err != nil {
+		return errors.Join(syncErr, fmt.Errorf("failed to update status with the synced rule groups: %w", err))
+	}
+
+	return syncErr
+}
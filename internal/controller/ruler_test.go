@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
+)
+
+// fakeRuler is a local stand-in for the ruler API of Grafana Mimir / Loki,
+// which stores all rule groups in memory.
+type fakeRuler struct {
+	mu     sync.Mutex
+	prefix string
+	// groups contains the rule groups by tenant, namespace and group name.
+	groups map[string]map[string]map[string]monitoringv1.RuleGroup
+}
+
+func newFakeRuler(prefix string) (*fakeRuler, *httptest.Server) {
+	ruler := &fakeRuler{
+		prefix: prefix,
+		groups: make(map[string]map[string]map[string]monitoringv1.RuleGroup),
+	}
+	return ruler, httptest.NewServer(ruler)
+}
+
+func (f *fakeRuler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tenant := r.Header.Get("X-Scope-OrgID")
+	if tenant == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), f.prefix+"/"), "/")
+	for i := range parts {
+		parts[i], _ = url.PathUnescape(parts[i])
+	}
+
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 1:
+		body, _ := io.ReadAll(r.Body)
+		var group monitoringv1.RuleGroup
+		// The ruler APIs of Mimir and Loki reject the fields of Thanos.
+		if err := yaml.UnmarshalStrict(body, &group); err != nil || group.PartialResponseStrategy != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.groups[tenant] == nil {
+			f.groups[tenant] = make(map[string]map[string]monitoringv1.RuleGroup)
+		}
+		if f.groups[tenant][parts[0]] == nil {
+			f.groups[tenant][parts[0]] = make(map[string]monitoringv1.RuleGroup)
+		}
+		f.groups[tenant][parts[0]][group.Name] = group
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodDelete && len(parts) == 2:
+		if _, ok := f.groups[tenant][parts[0]][parts[1]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.groups[tenant][parts[0]], parts[1])
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeRuler) groupNames(tenant, namespace string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for name := range f.groups[tenant][namespace] {
+		names = append(names, name)
+	}
+	return names
+}
+
+var _ = Describe("ServiceLevelObjective Controller (Mimir)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-mimir",
+			Namespace: "default",
+		}
+
+		var ruler *fakeRuler
+
+		BeforeEach(func() {
+			var server *httptest.Server
+			ruler, server = newFakeRuler("/prometheus/config/v1/rules")
+			DeferCleanup(server.Close)
+
+			sloOperatorMode = "mimir"
+			sloOperatorMimirURL = server.URL
+			DeferCleanup(func() {
+				sloOperatorMode = ""
+				sloOperatorMimirURL = ""
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-mimir",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					Tenant: "team-a",
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							RuleGroup: ricobergerdev1alpha1.RuleGroup{
+								PartialResponseStrategy: "warn",
+							},
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		reconcileResource := func() error {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			return err
+		}
+
+		It("Should sync, update and delete the rule groups", func() {
+			By("Reconciling the created resource")
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(ruler.groupNames("team-a", "test-mimir-default")).To(ConsistOf(
+				"slo-generic-test-mimir-default-availability",
+				"slo-errors-test-mimir-default-availability",
+				"slo-budget-test-mimir-default-availability",
+			))
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(resource, sloOperatorFinalizer)).To(BeTrue())
+			Expect(resource.Status.Ruler).To(Equal(&ricobergerdev1alpha1.RulerStatus{
+				Tenant:    "team-a",
+				Namespace: "test-mimir-default",
+				Groups: []string{
+					"slo-generic-test-mimir-default-availability",
+					"slo-errors-test-mimir-default-availability",
+					"slo-budget-test-mimir-default-availability",
+				},
+			}))
+
+			By("Renaming the SLO and changing the tenant")
+			resource.Spec.Tenant = "team-b"
+			resource.Spec.SLOs[0].Name = "errors"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(ruler.groupNames("team-a", "test-mimir-default")).To(BeEmpty())
+			Expect(ruler.groupNames("team-b", "test-mimir-default")).To(ConsistOf(
+				"slo-generic-test-mimir-default-errors",
+				"slo-errors-test-mimir-default-errors",
+				"slo-budget-test-mimir-default-errors",
+			))
+
+			By("Deleting the resource")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(ruler.groupNames("team-b", "test-mimir-default")).To(BeEmpty())
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("ServiceLevelObjective Controller (Loki)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-loki",
+			Namespace: "default",
+		}
+
+		var ruler *fakeRuler
+
+		BeforeEach(func() {
+			var server *httptest.Server
+			ruler, server = newFakeRuler("/loki/api/v1/rules")
+			DeferCleanup(server.Close)
+
+			sloOperatorLokiURL = server.URL
+			DeferCleanup(func() {
+				sloOperatorLokiURL = ""
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-loki",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+						{
+							Name:      "logs",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								Type:       "LogQL",
+								TotalQuery: `sum(count_over_time({app="api"} | json [${window}]))`,
+								ErrorQuery: `sum(count_over_time({app="api"} | json | status >= 500 [${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		reconcileResource := func() error {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			return err
+		}
+
+		It("Should sync the LogQL rule groups to Loki", func() {
+			By("Reconciling the created resource")
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(ruler.groupNames("fake", "test-loki-default")).To(ConsistOf(
+				"slo-generic-test-loki-default-logs",
+				"slo-errors-test-loki-default-logs",
+			))
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(resource, sloOperatorFinalizer)).To(BeTrue())
+			Expect(resource.Status.LokiRuler).To(Equal(&ricobergerdev1alpha1.RulerStatus{
+				Tenant:    "fake",
+				Namespace: "test-loki-default",
+				Groups: []string{
+					"slo-generic-test-loki-default-logs",
+					"slo-errors-test-loki-default-logs",
+				},
+			}))
+
+			By("Check if the PrometheusRule contains the rule groups using the recorded metrics")
+			prometheusRule := &monitoringv1.PrometheusRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+			var names []string
+			for _, group := range prometheusRule.Spec.Groups {
+				names = append(names, group.Name)
+			}
+			Expect(names).To(ConsistOf(
+				"slo-generic-test-loki-default-availability",
+				"slo-errors-test-loki-default-availability",
+				"slo-recorded-test-loki-default-logs",
+				"slo-budget-test-loki-default-availability",
+				"slo-budget-test-loki-default-logs",
+			))
+
+			By("Deleting the resource")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(ruler.groupNames("fake", "test-loki-default")).To(BeEmpty())
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
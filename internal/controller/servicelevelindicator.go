@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// resolveServiceLevelIndicators replaces the SLI of all SLOs, which are
+// referencing a ServiceLevelIndicator or a built-in preset, with the rendered
+// queries of the ServiceLevelIndicator / preset. The SLOs are only changed in
+// memory, so that the following steps of the reconciliation can use the SLI
+// as usual. The resolved SLIs must never be written back to the Kubernetes
+// API.
+func (r *ServiceLevelObjectiveReconciler) resolveServiceLevelIndicators(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) error {
+	for i := range slo.Spec.SLOs {
+		ref := slo.Spec.SLOs[i].SLI.Ref
+		preset := slo.Spec.SLOs[i].SLI.Preset
+		if ref == nil && preset == nil {
+			continue
+		}
+
+		if (ref != nil && preset != nil) || slo.Spec.SLOs[i].SLI.TotalQuery != "" || slo.Spec.SLOs[i].SLI.ErrorQuery != "" {
+			return fmt.Errorf("slo %s must either reference a ServiceLevelIndicator, use a preset or define the queries", slo.Spec.SLOs[i].Name)
+		}
+
+		if preset != nil {
+			if err := validateSLIPreset(slo.Spec.SLOs[i]); err != nil {
+				return fmt.Errorf("invalid preset %s of slo %s: %w", preset.Name, slo.Spec.SLOs[i].Name, err)
+			}
+
+			sli, err := renderSLIPreset(*preset)
+			if err != nil {
+				return fmt.Errorf("failed to render preset %s of slo %s: %w", preset.Name, slo.Spec.SLOs[i].Name, err)
+			}
+			sli.Preset = preset
+
+			slo.Spec.SLOs[i].SLI = sli
+			continue
+		}
+
+		name := types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}
+		if name.Namespace == "" {
+			name.Namespace = slo.Namespace
+		}
+
+		indicator := &ricobergerdev1alpha1.ServiceLevelIndicator{}
+		if err := r.Get(ctx, name, indicator); err != nil {
+			return fmt.Errorf("failed to get ServiceLevelIndicator %s of slo %s: %w", name, slo.Spec.SLOs[i].Name, err)
+		}
+
+		sli, err := renderServiceLevelIndicator(indicator.Spec, ref.Parameters)
+		if err != nil {
+			return fmt.Errorf("failed to render ServiceLevelIndicator %s of slo %s: %w", name, slo.Spec.SLOs[i].Name, err)
+		}
+		sli.Ref = ref
+
+		slo.Spec.SLOs[i].SLI = sli
+	}
+
+	return nil
+}
+
+// renderServiceLevelIndicator returns the SLI for the provided
+// ServiceLevelIndicator, where the placeholders of all parameters in the
+// queries are replaced by the provided values or the default values of the
+// parameters. The "${window}" placeholder is kept, so that it can be replaced
+// when the rules are generated. Since only the parameters are available in
+// the queries, an error is returned for all other variables.
+func renderServiceLevelIndicator(spec ricobergerdev1alpha1.ServiceLevelIndicatorSpec, values map[string]string) (ricobergerdev1alpha1.SLI, error) {
+	for name := range values {
+		if !slices.ContainsFunc(spec.Parameters, func(parameter ricobergerdev1alpha1.Parameter) bool {
+			return parameter.Name == name
+		}) {
+			return ricobergerdev1alpha1.SLI{}, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	vars := map[string]string{"window": "${window}"}
+	for _, parameter := range spec.Parameters {
+		if parameter.Name == "window" {
+			return ricobergerdev1alpha1.SLI{}, fmt.Errorf("parameter name window is reserved")
+		}
+
+		value, ok := values[parameter.Name]
+		if !ok {
+			if parameter.Default == nil {
+				return ricobergerdev1alpha1.SLI{}, fmt.Errorf("required parameter %s is missing", parameter.Name)
+			}
+			value = *parameter.Default
+		}
+		vars[parameter.Name] = value
+	}
+
+	totalQuery, err := renderQuery(spec.TotalQuery, vars)
+	if err != nil {
+		return ricobergerdev1alpha1.SLI{}, fmt.Errorf("total query: %w", err)
+	}
+	errorQuery, err := renderQuery(spec.ErrorQuery, vars)
+	if err != nil {
+		return ricobergerdev1alpha1.SLI{}, fmt.Errorf("error query: %w", err)
+	}
+
+	return ricobergerdev1alpha1.SLI{
+		Type:       spec.Type,
+		TotalQuery: totalQuery,
+		ErrorQuery: errorQuery,
+	}, nil
+}
+
+// serviceLevelIndicatorRefField is the name of the field index, which contains
+// all ServiceLevelIndicators referenced by the SLOs of a ServiceLevelObjective
+// in the form "<namespace>/<name>".
+const serviceLevelIndicatorRefField = ".spec.slos.sli.ref"
+
+// indexServiceLevelIndicatorRefs returns the values for the
+// serviceLevelIndicatorRefField index of the provided ServiceLevelObjective.
+// When the namespace of a reference is not set, the namespace of the
+// ServiceLevelObjective is used.
+func indexServiceLevelIndicatorRefs(obj client.Object) []string {
+	slo, ok := obj.(*ricobergerdev1alpha1.ServiceLevelObjective)
+	if !ok {
+		return nil
+	}
+
+	var refs []string
+	for _, s := range slo.Spec.SLOs {
+		if s.SLI.Ref == nil {
+			continue
+		}
+
+		namespace := s.SLI.Ref.Namespace
+		if namespace == "" {
+			namespace = slo.Namespace
+		}
+
+		ref := types.NamespacedName{Name: s.SLI.Ref.Name, Namespace: namespace}.String()
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// findServiceLevelObjectivesForServiceLevelIndicator returns a reconcile
+// request for each ServiceLevelObjective, which contains a SLO referencing the
+// provided ServiceLevelIndicator, so that the rules of all dependent
+// ServiceLevelObjectives are re-rendered when the ServiceLevelIndicator is
+// changed. The ServiceLevelObjectives are looked up via the
+// serviceLevelIndicatorRefField index.
+func (r *ServiceLevelObjectiveReconciler) findServiceLevelObjectivesForServiceLevelIndicator(ctx context.Context, indicator client.Object) []reconcile.Request {
+	serviceLevelObjectives := &ricobergerdev1alpha1.ServiceLevelObjectiveList{}
+	if err := r.List(ctx, serviceLevelObjectives, client.MatchingFields{serviceLevelIndicatorRefField: client.ObjectKeyFromObject(indicator).String()}); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list ServiceLevelObjectives.")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, slo := range serviceLevelObjectives.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}})
+	}
+
+	return requests
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ServiceLevelObjective Controller (ServiceLevelIndicator)", func() {
+	Context("When reconciling a resource referencing a ServiceLevelIndicator", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-indicator",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("Creating the custom resource for the Kind ServiceLevelIndicator")
+			Expect(k8sClient.Create(ctx, &ricobergerdev1alpha1.ServiceLevelIndicator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingress-nginx",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelIndicatorSpec{
+					TotalQuery: `sum(rate(nginx_ingress_controller_requests{host="${host}"}[${window}]))`,
+					ErrorQuery: `sum(rate(nginx_ingress_controller_requests{host="${host}",status=~"${status}"}[${window}]))`,
+					Parameters: []ricobergerdev1alpha1.Parameter{
+						{Name: "host"},
+						{Name: "status", Default: ptr.To("5..")},
+					},
+				},
+			})).To(Succeed())
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			Expect(k8sClient.Create(ctx, &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-indicator",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								Ref: &ricobergerdev1alpha1.ServiceLevelIndicatorRef{
+									Name:       "ingress-nginx",
+									Parameters: map[string]string{"host": "example.com"},
+								},
+							},
+						},
+					},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			indicator := &ricobergerdev1alpha1.ServiceLevelIndicator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "ingress-nginx", Namespace: "default"}, indicator)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, indicator)).To(Succeed())
+		})
+
+		It("Should render the queries of the ServiceLevelIndicator", func() {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			prometheusRule := &monitoringv1.PrometheusRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+			Expect(prometheusRule.Spec.Groups[0].Rules[2].Record).To(Equal("slo:total"))
+			Expect(prometheusRule.Spec.Groups[0].Rules[2].Expr.String()).To(Equal(`sum(rate(nginx_ingress_controller_requests{host="example.com"}[2m]))`))
+
+			By("Keeping the reference in the ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Spec.SLOs[0].SLI.TotalQuery).To(BeEmpty())
+
+			By("Re-rendering the rules when the ServiceLevelIndicator is changed")
+			indicator := &ricobergerdev1alpha1.ServiceLevelIndicator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "ingress-nginx", Namespace: "default"}, indicator)).To(Succeed())
+			indicator.Spec.TotalQuery = `sum(rate(nginx_ingress_controller_requests{host="${host}",method!="OPTIONS"}[${window}]))`
+			Expect(k8sClient.Update(ctx, indicator)).To(Succeed())
+
+			// The field index is only available in the cache of the manager, so
+			// that the ServiceLevelObjectives are looked up in a fake client.
+			indexedClient := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithIndex(&ricobergerdev1alpha1.ServiceLevelObjective{}, serviceLevelIndicatorRefField, indexServiceLevelIndicatorRefs).
+				WithObjects(resource).
+				Build()
+			requests := (&ServiceLevelObjectiveReconciler{Client: indexedClient}).findServiceLevelObjectivesForServiceLevelIndicator(ctx, indicator)
+			Expect(requests).To(ConsistOf(reconcile.Request{NamespacedName: typeNamespacedName}))
+
+			_, err = controllerReconciler.Reconcile(ctx, requests[0])
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+			Expect(prometheusRule.Spec.Groups[0].Rules[2].Expr.String()).To(Equal(`sum(rate(nginx_ingress_controller_requests{host="example.com",method!="OPTIONS"}[2m]))`))
+		})
+	})
+})
+
+var _ = Describe("indexServiceLevelIndicatorRefs", func() {
+	It("Should return the referenced ServiceLevelIndicators", func() {
+		Expect(indexServiceLevelIndicatorRefs(&ricobergerdev1alpha1.ServiceLevelObjective{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "team-a",
+			},
+			Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+				SLOs: []ricobergerdev1alpha1.SLO{
+					{Name: "availability", SLI: ricobergerdev1alpha1.SLI{Ref: &ricobergerdev1alpha1.ServiceLevelIndicatorRef{Name: "ingress-nginx"}}},
+					{Name: "availability-api", SLI: ricobergerdev1alpha1.SLI{Ref: &ricobergerdev1alpha1.ServiceLevelIndicatorRef{Name: "ingress-nginx", Namespace: "team-a"}}},
+					{Name: "latency", SLI: ricobergerdev1alpha1.SLI{Ref: &ricobergerdev1alpha1.ServiceLevelIndicatorRef{Name: "latency", Namespace: "monitoring"}}},
+					{Name: "errors", SLI: ricobergerdev1alpha1.SLI{TotalQuery: "vector(1)", ErrorQuery: "vector(0)"}},
+				},
+			},
+		})).To(Equal([]string{"team-a/ingress-nginx", "monitoring/latency"}))
+	})
+})
+
+var _ = Describe("renderServiceLevelIndicator", func() {
+	spec := ricobergerdev1alpha1.ServiceLevelIndicatorSpec{
+		Type:       "PromQL",
+		TotalQuery: `sum(rate(nginx_ingress_controller_requests{host="${host}"}[${window}]))`,
+		ErrorQuery: `sum(rate(nginx_ingress_controller_requests{host="${host}",status=~"${status}"}[${window}]))`,
+		Parameters: []ricobergerdev1alpha1.Parameter{
+			{Name: "host"},
+			{Name: "status", Default: ptr.To("5..")},
+		},
+	}
+
+	It("Should replace the parameters", func() {
+		sli, err := renderServiceLevelIndicator(spec, map[string]string{"host": "example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sli.Type).To(Equal("PromQL"))
+		Expect(sli.TotalQuery).To(Equal(`sum(rate(nginx_ingress_controller_requests{host="example.com"}[${window}]))`))
+		Expect(sli.ErrorQuery).To(Equal(`sum(rate(nginx_ingress_controller_requests{host="example.com",status=~"5.."}[${window}]))`))
+
+		sli, err = renderServiceLevelIndicator(spec, map[string]string{"host": "example.com", "status": "5..|429"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sli.ErrorQuery).To(Equal(`sum(rate(nginx_ingress_controller_requests{host="example.com",status=~"5..|429"}[${window}]))`))
+	})
+
+	It("Should fail for missing or unknown parameters", func() {
+		_, err := renderServiceLevelIndicator(spec, nil)
+		Expect(err).To(HaveOccurred())
+
+		_, err = renderServiceLevelIndicator(spec, map[string]string{"host": "example.com", "path": "/"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +15,9 @@ import (
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,12 +25,51 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/yaml"
 )
 
-var sloOperatorMode = strings.ToLower(os.Getenv("SLO_OPERATOR_MODE"))
+var (
+	sloOperatorMode                = strings.ToLower(os.Getenv("SLO_OPERATOR_MODE"))
+	sloOperatorPrometheusReloadURL = os.Getenv("SLO_OPERATOR_PROMETHEUS_RELOAD_URL")
+	sloOperatorPrometheusURL       = os.Getenv("SLO_OPERATOR_PROMETHEUS_URL")
+	sloOperatorMimirURL            = os.Getenv("SLO_OPERATOR_MIMIR_URL")
+	sloOperatorLokiURL             = os.Getenv("SLO_OPERATOR_LOKI_URL")
+	sloOperatorTenantLabel         = os.Getenv("SLO_OPERATOR_TENANT_LABEL")
+
+	sloOperatorDashboardMode             = strings.ToLower(os.Getenv("SLO_OPERATOR_DASHBOARD_MODE"))
+	sloOperatorDashboardInstanceSelector = os.Getenv("SLO_OPERATOR_DASHBOARD_INSTANCE_SELECTOR")
+
+	sloOperatorAlertingMode         = strings.ToLower(os.Getenv("SLO_OPERATOR_ALERTING_MODE"))
+	sloOperatorGrafanaDatasourceUID = os.Getenv("SLO_OPERATOR_GRAFANA_DATASOURCE_UID")
+	sloOperatorGrafanaFolder        = os.Getenv("SLO_OPERATOR_GRAFANA_FOLDER")
+	sloOperatorGrafanaOrgID         = os.Getenv("SLO_OPERATOR_GRAFANA_ORG_ID")
+
+	sloOperatorMaintenanceMode = strings.ToLower(os.Getenv("SLO_OPERATOR_MAINTENANCE_MODE"))
+	sloOperatorAlertmanagerURL = os.Getenv("SLO_OPERATOR_ALERTMANAGER_URL")
+)
+
+// sloOperatorFinalizer is the finalizer which is added to ServiceLevelObjective
+// resources, when the operator creates rules outside of the Kubernetes cluster,
+// which can not be garbage collected via an owner reference.
+const sloOperatorFinalizer = "slo-operator.ricoberger.de/finalizer"
+
+// statusRefreshInterval is the interval in which the live status of the SLOs
+// is refreshed, when the "SLO_OPERATOR_PROMETHEUS_URL" environment variable is
+// set.
+const statusRefreshInterval = 5 * time.Minute
+
+// httpClient is the client used for all requests against external APIs, like
+// the ruler API of Grafana Mimir.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// prometheusReloadTimeout is the timeout for the reload of Prometheus, so that
+// a slow reload doesn't block the reconciliation.
+const prometheusReloadTimeout = 10 * time.Second
 
 // ServiceLevelObjectiveReconciler reconciles a ServiceLevelObjective object
 type ServiceLevelObjectiveReconciler struct {
@@ -36,8 +80,13 @@ type ServiceLevelObjectiveReconciler struct {
 // +kubebuilder:rbac:groups=ricoberger.de,resources=servicelevelobjectives,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ricoberger.de,resources=servicelevelobjectives/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ricoberger.de,resources=servicelevelobjectives/finalizers,verbs=update
+// +kubebuilder:rbac:groups=ricoberger.de,resources=servicelevelindicators,verbs=get;list;watch
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmrules,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=alertmanagerconfigs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=grafana.integreatly.org,resources=grafanadashboards,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
@@ -66,6 +115,36 @@ func (r *ServiceLevelObjectiveReconciler) Reconcile(ctx context.Context, req ctr
 		return ctrl.Result{}, err
 	}
 
+	// If the ServiceLevelObjective is marked for deletion, we have to delete
+	// all rules, which were created outside of the Kubernetes cluster, before
+	// we can remove our finalizer.
+	if !serviceLevelObjective.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(serviceLevelObjective, sloOperatorFinalizer) {
+			err = r.finalizeServiceLevelObjective(ctx, serviceLevelObjective)
+			if err != nil {
+				reqLogger.Error(err, "Failed to finalize ServiceLevelObjective.")
+				return ctrl.Result{}, err
+			}
+
+			controllerutil.RemoveFinalizer(serviceLevelObjective, sloOperatorFinalizer)
+			err = r.Update(ctx, serviceLevelObjective)
+			if err != nil {
+				reqLogger.Error(err, "Failed to remove finalizer.")
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if (sloOperatorMode == "mimir" || sloOperatorLokiURL != "" || sloOperatorMaintenanceMode == "silences") && controllerutil.AddFinalizer(serviceLevelObjective, sloOperatorFinalizer) {
+		err = r.Update(ctx, serviceLevelObjective)
+		if err != nil {
+			reqLogger.Error(err, "Failed to add finalizer.")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Define the labels, which should be added to the generated Prometheus
 	// rules. A user can define custom labels for the metrics via the
 	// "slo-operator.ricoberger.de/<NAME>: <VALUE>" labels.
@@ -91,18 +170,135 @@ func (r *ServiceLevelObjectiveReconciler) Reconcile(ctx context.Context, req ctr
 	// For each of the specified SLO we generate two Prometheus rule groups. One
 	// contains the generic metrics and the other one the burn rates and
 	// corresponding alerts.
+	//
+	// We also remember the VictoriaMetrics specific options for each group,
+	// because they can not be set in the Prometheus rule groups and are only
+	// needed when we convert the groups for a VMRule.
+	//
+	// When the operator runs in the "victoriametrics" mode, missing error
+	// series are filled via the "default" operator of MetricsQL, all other
+	// expressions are the same as for Prometheus. The recordings of SLOs with a
+	// LogQL SLI are generated in LogQL and collected in a separate list of
+	// groups, because they must be evaluated by the Loki ruler. The remaining
+	// rules of these SLOs are only using the recorded metrics and are evaluated
+	// together with the other groups.
 	var groups []monitoringv1.RuleGroup
+	var lokiGroups []monitoringv1.RuleGroup
+	vmOptions := make(map[string]ricobergerdev1alpha1.VictoriaMetricsRuleGroup)
+
+	language := promQL
+	if sloOperatorMode == "victoriametrics" {
+		language = metricsQL
+	}
+
+	// SLOs can reference a ServiceLevelIndicator instead of defining the
+	// queries inline, so that we have to render the referenced queries before
+	// we can generate the rules.
+	err = r.resolveServiceLevelIndicators(ctx, serviceLevelObjective)
+	if err != nil {
+		reqLogger.Error(err, "Failed to resolve ServiceLevelIndicators.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
+
+	// Composite SLOs reference other SLOs, which must exist, because
+	// otherwise the composite SLO would never have any data.
+	err = r.validateCompositeSLOs(ctx, serviceLevelObjective)
+	if err != nil {
+		reqLogger.Error(err, "Failed to validate composite SLOs.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
 
 	for _, slo := range serviceLevelObjective.Spec.SLOs {
-		sloGroups, err := generatePrometheusRuleGroup(slo, labels)
+		if strings.EqualFold(slo.SLI.Type, "logql") {
+			sloGroups, err := generatePrometheusRuleGroup(slo, labels, logQL)
+			if err != nil {
+				reqLogger.Error(err, "Failed to generate Loki rule group for SLO.", "slo", slo.Name)
+				r.updateConditions(ctx, serviceLevelObjective, err)
+				return ctrl.Result{}, err
+			}
+			lokiGroups = append(lokiGroups, sloGroups[:2]...)
+			for _, group := range sloGroups[2:] {
+				vmOptions[group.Name] = slo.RuleGroup.VictoriaMetrics
+			}
+			groups = append(groups, sloGroups[2:]...)
+			continue
+		}
+
+		sloGroups, err := generatePrometheusRuleGroup(slo, labels, language)
 		if err != nil {
 			reqLogger.Error(err, "Failed to generate PrometheusRuleGroup for SLO.", "slo", slo.Name)
 			r.updateConditions(ctx, serviceLevelObjective, err)
 			return ctrl.Result{}, err
 		}
+		for _, group := range sloGroups {
+			vmOptions[group.Name] = slo.RuleGroup.VictoriaMetrics
+		}
 		groups = append(groups, sloGroups...)
 	}
 
+	// The error budget rules are only using the recorded "slo:*" metrics, so
+	// that they can be evaluated by Prometheus / VictoriaMetrics for all SLOs,
+	// also for SLOs with a LogQL SLI, because the Loki ruler writes the
+	// recorded metrics to Prometheus.
+	for _, slo := range serviceLevelObjective.Spec.SLOs {
+		budgetGroups, err := generateErrorBudgetRuleGroups(slo, labels)
+		if err != nil {
+			reqLogger.Error(err, "Failed to generate error budget rule group for SLO.", "slo", slo.Name)
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+		for _, group := range budgetGroups {
+			vmOptions[group.Name] = slo.RuleGroup.VictoriaMetrics
+		}
+		groups = append(groups, budgetGroups...)
+	}
+
+	// The maintenance windows are excluded from the availability and error
+	// budget and suppress the burn rate and absent alerts. Recurring
+	// maintenance windows are only added for the next days, so that we have to
+	// regenerate the rules periodically. When the maintenance mode is set to
+	// "silences", the rules are not changed and Alertmanager silences are
+	// created for the maintenance windows instead.
+	maintenanceRanges, err := generateMaintenanceRanges(serviceLevelObjective.Spec.MaintenanceWindows, timeNow())
+	if err != nil {
+		reqLogger.Error(err, "Failed to generate maintenance windows.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
+	if sloOperatorMaintenanceMode == "silences" {
+		err = reconcileSilences(ctx, serviceLevelObjective, maintenanceRanges)
+		if err != nil {
+			reqLogger.Error(err, "Failed to reconcile Alertmanager silences.")
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+	} else {
+		err = validateMaintenanceWindows(serviceLevelObjective.Spec)
+		if err != nil {
+			reqLogger.Error(err, "Failed to validate maintenance windows.")
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+		groups = applyMaintenanceWindows(groups, maintenanceRanges)
+	}
+
+	// When the alerting mode is set to "grafana", the alerts are removed from
+	// the generated groups and provisioned as Grafana alert rules instead,
+	// while the recording rules are still created for Prometheus.
+	if sloOperatorAlertingMode == "grafana" {
+		var alertingGroups []monitoringv1.RuleGroup
+		groups, alertingGroups = splitAlertingRules(groups)
+
+		err = r.reconcileGrafanaAlerts(ctx, serviceLevelObjective, alertingGroups)
+		if err != nil {
+			reqLogger.Error(err, "Failed to reconcile Grafana alerts.")
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+	}
+
 	// The operator can work in different modes. The mode can be set via the \
 	// "SLO_OPERATOR_MODE" environment variable and defines the resource which
 	// should be created by the operator.
@@ -110,15 +306,33 @@ func (r *ServiceLevelObjectiveReconciler) Reconcile(ctx context.Context, req ctr
 	// By default we create a "PrometheusRule" for the Prometheus Operator, but
 	// the operator can also create a "VMRule" for the VictoriaMetrics Operator,
 	// by converting the PrometheusRule, when the mode is set to
-	// "victoriametrics".
-	if sloOperatorMode == "victoriametrics" {
-		err = r.reconcileVMRule(ctx, serviceLevelObjective, groups)
+	// "victoriametrics". For clusters without an operator, the mode can be set
+	// to "configmap", to write the rules as Prometheus rule file into a
+	// ConfigMap. When the mode is set to "mimir", the rules are pushed to the
+	// ruler API of Grafana Mimir.
+	switch sloOperatorMode {
+	case "victoriametrics":
+		err = r.reconcileVMRule(ctx, serviceLevelObjective, groups, vmOptions)
 		if err != nil {
 			reqLogger.Error(err, "Failed to reconcile VMRule.")
 			r.updateConditions(ctx, serviceLevelObjective, err)
 			return ctrl.Result{}, err
 		}
-	} else {
+	case "configmap":
+		err = r.reconcileConfigMap(ctx, serviceLevelObjective, groups)
+		if err != nil {
+			reqLogger.Error(err, "Failed to reconcile ConfigMap.")
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+	case "mimir":
+		err = r.reconcileMimir(ctx, serviceLevelObjective, groups)
+		if err != nil {
+			reqLogger.Error(err, "Failed to reconcile Mimir rules.")
+			r.updateConditions(ctx, serviceLevelObjective, err)
+			return ctrl.Result{}, err
+		}
+	default:
 		err = r.reconcilePrometheusRule(ctx, serviceLevelObjective, groups)
 		if err != nil {
 			reqLogger.Error(err, "Failed to reconcile PrometheusRule.")
@@ -127,8 +341,73 @@ func (r *ServiceLevelObjectiveReconciler) Reconcile(ctx context.Context, req ctr
 		}
 	}
 
+	// The rule groups for the SLOs with a LogQL SLI are always pushed to the
+	// ruler API of Grafana Loki, independent of the configured mode.
+	err = r.reconcileLoki(ctx, serviceLevelObjective, lokiGroups)
+	if err != nil {
+		reqLogger.Error(err, "Failed to reconcile Loki rules.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
+
+	err = r.reconcileDashboard(ctx, serviceLevelObjective, labels)
+	if err != nil {
+		reqLogger.Error(err, "Failed to reconcile Grafana dashboard.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
+
+	err = r.reconcileAlertmanagerConfig(ctx, serviceLevelObjective)
+	if err != nil {
+		reqLogger.Error(err, "Failed to reconcile AlertmanagerConfig.")
+		r.updateConditions(ctx, serviceLevelObjective, err)
+		return ctrl.Result{}, err
+	}
+
+	// For SLOs with a calendar window the current period is added to the
+	// status. Since the rules of these SLOs must be regenerated for each
+	// period, we requeue the ServiceLevelObjective at the end of the period.
+	statuses, requeueAfter := generateSLOPeriodStatuses(serviceLevelObjective.Spec.SLOs)
+	if requeueAfter > 0 {
+		serviceLevelObjective.Status.SLOs = statuses
+	} else {
+		serviceLevelObjective.Status.SLOs = nil
+	}
+
+	for _, window := range serviceLevelObjective.Spec.MaintenanceWindows {
+		if window.Schedule != "" && (requeueAfter == 0 || maintenanceRefreshInterval < requeueAfter) {
+			requeueAfter = maintenanceRefreshInterval
+		}
+	}
+
+	// In the silences mode the state of the silences in the status changes at
+	// the start and end of each maintenance window, so that we requeue the
+	// ServiceLevelObjective at the next start or end.
+	if sloOperatorMaintenanceMode == "silences" {
+		if next := nextMaintenanceChange(maintenanceRanges, timeNow()); next > 0 && (requeueAfter == 0 || next < requeueAfter) {
+			requeueAfter = next
+		}
+	}
+
+	// If the URL of Prometheus is configured, we query the live status of the
+	// SLOs and requeue the ServiceLevelObjective, so that the status is
+	// refreshed periodically. An error is only logged, because the rules were
+	// reconciled successfully.
+	if sloOperatorPrometheusURL != "" {
+		serviceLevelObjective.Status.SLOs = statuses
+
+		err = updateSLOStatus(ctx, serviceLevelObjective)
+		if err != nil {
+			reqLogger.Error(err, "Failed to update live status of SLOs.")
+		}
+
+		if requeueAfter == 0 || statusRefreshInterval < requeueAfter {
+			requeueAfter = statusRefreshInterval
+		}
+	}
+
 	r.updateConditions(ctx, serviceLevelObjective, nil)
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // reconcilePrometheusRule creates / updates the PrometheusRule for a
@@ -181,10 +460,18 @@ func (r *ServiceLevelObjectiveReconciler) reconcilePrometheusRule(ctx context.Co
 }
 
 // reconcileVMRule creates / updates the VMRule for a ServiceLevelObjective
-// resource.
-func (r *ServiceLevelObjectiveReconciler) reconcileVMRule(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup) error {
+// resource. The VictoriaMetrics specific options for the groups are looked up
+// by the group name in the provided vmOptions.
+func (r *ServiceLevelObjectiveReconciler) reconcileVMRule(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup, vmOptions map[string]ricobergerdev1alpha1.VictoriaMetricsRuleGroup) error {
 	reqLogger := log.FromContext(ctx)
 
+	// When vmalert runs in multi-tenant mode, all groups are created for the
+	// tenant of the ServiceLevelObjective.
+	tenant, err := r.getTenant(ctx, slo)
+	if err != nil {
+		return err
+	}
+
 	// Since the operator creates a PrometheusRule by default, we have to
 	// convert the groups to VictoriaMetrics rule groups first. The convert
 	// logic is heavily inspired by the logic used by the VictoriaMetrics
@@ -192,35 +479,8 @@ func (r *ServiceLevelObjectiveReconciler) reconcileVMRule(ctx context.Context, s
 	//
 	// See https://github.com/VictoriaMetrics/operator/blob/a6729aa4a430b4bc5d1d061e8e9ce3af3f884120/internal/controller/operator/converter/apis.go#L24
 	vmGroups := make([]vmv1beta1.RuleGroup, 0, len(groups))
-
 	for _, group := range groups {
-		vmRules := make([]vmv1beta1.Rule, 0, len(group.Rules))
-		for _, rule := range group.Rules {
-			trule := vmv1beta1.Rule{
-				Labels:      rule.Labels,
-				Annotations: rule.Annotations,
-				Expr:        rule.Expr.String(),
-				Record:      rule.Record,
-				Alert:       rule.Alert,
-			}
-
-			if rule.For != nil {
-				trule.For = string(*rule.For)
-			}
-
-			vmRules = append(vmRules, trule)
-		}
-
-		tgroup := vmv1beta1.RuleGroup{
-			Name:  group.Name,
-			Rules: vmRules,
-		}
-
-		if group.Interval != nil {
-			tgroup.Interval = string(*group.Interval)
-		}
-
-		vmGroups = append(vmGroups, tgroup)
+		vmGroups = append(vmGroups, convertVMRuleGroup(group, tenant, vmOptions[group.Name]))
 	}
 
 	// At this point we can use the convert groups and create / update a VMRule.
@@ -237,7 +497,7 @@ func (r *ServiceLevelObjectiveReconciler) reconcileVMRule(ctx context.Context, s
 		},
 	}
 
-	err := ctrl.SetControllerReference(slo, vmRule, r.Scheme)
+	err = ctrl.SetControllerReference(slo, vmRule, r.Scheme)
 	if err != nil {
 		return err
 	}
@@ -269,6 +529,327 @@ func (r *ServiceLevelObjectiveReconciler) reconcileVMRule(ctx context.Context, s
 	return nil
 }
 
+// convertVMRuleGroup converts a Prometheus rule group to a VictoriaMetrics rule
+// group. The query offset of the Prometheus rule group is used as "eval_delay",
+// which has the same semantic in vmalert. The partial response strategy is only
+// supported by Thanos Ruler and therefore ignored. The tenant and the
+// VictoriaMetrics specific options are added to the converted group.
+func convertVMRuleGroup(group monitoringv1.RuleGroup, tenant string, options ricobergerdev1alpha1.VictoriaMetricsRuleGroup) vmv1beta1.RuleGroup {
+	vmRules := make([]vmv1beta1.Rule, 0, len(group.Rules))
+	for _, rule := range group.Rules {
+		trule := vmv1beta1.Rule{
+			Labels:      rule.Labels,
+			Annotations: rule.Annotations,
+			Expr:        rule.Expr.String(),
+			Record:      rule.Record,
+			Alert:       rule.Alert,
+		}
+
+		if rule.For != nil {
+			trule.For = string(*rule.For)
+		}
+
+		vmRules = append(vmRules, trule)
+	}
+
+	tgroup := vmv1beta1.RuleGroup{
+		Name:        group.Name,
+		Rules:       vmRules,
+		Labels:      group.Labels,
+		Tenant:      tenant,
+		EvalOffset:  options.EvalOffset,
+		Concurrency: options.Concurrency,
+		Params:      options.Params,
+		Headers:     options.Headers,
+	}
+
+	if group.Interval != nil {
+		tgroup.Interval = string(*group.Interval)
+	}
+
+	if group.QueryOffset != nil {
+		tgroup.EvalDelay = string(*group.QueryOffset)
+	}
+
+	if group.Limit != nil {
+		tgroup.Limit = *group.Limit
+	}
+
+	return tgroup
+}
+
+// reconcileConfigMap creates / updates a ConfigMap, which contains the rule
+// groups for a ServiceLevelObjective resource in the Prometheus rule file
+// format. The ConfigMap can then be mounted into a Prometheus instance, which
+// isn't managed by an operator.
+//
+// The ConfigMap contains a single key "<namespace>-<name>.yaml", so that
+// multiple ConfigMaps can be mounted into the same directory, and it has the
+// "slo-operator.ricoberger.de/rules: true" label, so that it can be selected by
+// a sidecar. If the "SLO_OPERATOR_PROMETHEUS_RELOAD_URL" environment variable
+// is set, we also trigger a reload of the Prometheus configuration, when the
+// content of the ConfigMap was changed.
+func (r *ServiceLevelObjectiveReconciler) reconcileConfigMap(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup) error {
+	reqLogger := log.FromContext(ctx)
+
+	rules, err := yaml.Marshal(monitoringv1.PrometheusRuleSpec{Groups: groups})
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      slo.Name,
+			Namespace: slo.Namespace,
+			Labels: map[string]string{
+				"slo-operator.ricoberger.de/rules": "true",
+			},
+		},
+		Data: map[string]string{
+			fmt.Sprintf("%s-%s.yaml", slo.Namespace, slo.Name): string(rules),
+		},
+	}
+
+	err = ctrl.SetControllerReference(slo, configMap, r.Scheme)
+	if err != nil {
+		return err
+	}
+
+	found := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: slo.Name, Namespace: slo.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new ConfigMap.")
+		err = r.Create(ctx, configMap)
+		if err != nil {
+			reqLogger.Error(err, "Failed to create ConfigMap.")
+			return err
+		}
+
+		reloadPrometheus(ctx)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// If the rules didn't change, we can skip the update of the ConfigMap and
+	// more importantly the reload of Prometheus.
+	if maps.Equal(found.Data, configMap.Data) && maps.Equal(found.Labels, configMap.Labels) {
+		return nil
+	}
+
+	reqLogger.Info("Updating an existing ConfigMap.")
+	configMap.ResourceVersion = found.ResourceVersion
+
+	err = r.Update(ctx, configMap)
+	if err != nil {
+		reqLogger.Error(err, "Failed to update ConfigMap.")
+		return err
+	}
+
+	reloadPrometheus(ctx)
+	return nil
+}
+
+// reloadPrometheus triggers a reload of the Prometheus configuration via the
+// "/-/reload" endpoint, when the "SLO_OPERATOR_PROMETHEUS_RELOAD_URL"
+// environment variable is set. Prometheus must be started with the
+// "--web.enable-lifecycle" flag for this to work.
+//
+// A failed reload is only logged, because the ConfigMap was already changed
+// and Prometheus will pick up the changes with its next reload anyway. Since
+// the kubelet syncs changes of a mounted ConfigMap with a delay, the reload
+// can still see the old rules, so that a config-reloader sidecar should be
+// preferred.
+func reloadPrometheus(ctx context.Context) {
+	if sloOperatorPrometheusReloadURL == "" {
+		return
+	}
+
+	reqLogger := log.FromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, prometheusReloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/-/reload", strings.TrimSuffix(sloOperatorPrometheusReloadURL, "/")), http.NoBody)
+	if err != nil {
+		reqLogger.Error(err, "Failed to reload Prometheus.")
+		return
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		reqLogger.Error(err, "Failed to reload Prometheus.")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reqLogger.Error(fmt.Errorf("unexpected status code %d", resp.StatusCode), "Failed to reload Prometheus.")
+	}
+}
+
+// reconcileMimir syncs the rule groups for a ServiceLevelObjective resource to
+// the ruler API of Grafana Mimir, which is configured via the
+// "SLO_OPERATOR_MIMIR_URL" environment variable.
+//
+// All rule groups are created in the "<name>-<namespace>" namespace of the
+// tenant returned by the getTenant function. If no tenant is configured, we
+// use the "anonymous" tenant, which is used by Mimir when multi-tenancy is
+// disabled. The created groups are tracked in the status of the
+// ServiceLevelObjective, so that we can delete them, when they are not
+// generated anymore or when the ServiceLevelObjective is deleted.
+func (r *ServiceLevelObjectiveReconciler) reconcileMimir(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup) error {
+	tenant, err := r.getTenant(ctx, slo)
+	if err != nil {
+		return err
+	}
+	if tenant == "" {
+		tenant = "anonymous"
+	}
+
+	status, err := syncRuler(ctx, mimirRulerClient(), slo.Status.Ruler, tenant, fmt.Sprintf("%s-%s", slo.Name, slo.Namespace), groups)
+	changed := !equality.Semantic.DeepEqual(slo.Status.Ruler, status)
+	slo.Status.Ruler = status
+	return r.updateRulerStatus(ctx, slo, changed, err)
+}
+
+// mimirRulerClient returns a client for the ruler API of Grafana Mimir.
+func mimirRulerClient() *rulerClient {
+	return &rulerClient{
+		address: sloOperatorMimirURL,
+		prefix:  "/prometheus/config/v1/rules",
+	}
+}
+
+// reconcileLoki syncs the rule groups for the SLOs with a LogQL SLI to the
+// ruler API of Grafana Loki, which is configured via the
+// "SLO_OPERATOR_LOKI_URL" environment variable.
+//
+// The groups are created in the "<name>-<namespace>" namespace of the tenant
+// returned by the getTenant function. If no tenant is configured, we use the
+// "fake" tenant, which is used by Loki when multi-tenancy is disabled. Like
+// for Grafana Mimir the created groups are tracked in the status of the
+// ServiceLevelObjective.
+func (r *ServiceLevelObjectiveReconciler) reconcileLoki(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, groups []monitoringv1.RuleGroup) error {
+	if sloOperatorLokiURL == "" {
+		if len(groups) > 0 {
+			return fmt.Errorf("slos with a logql sli require the SLO_OPERATOR_LOKI_URL environment variable")
+		}
+		return nil
+	}
+
+	tenant, err := r.getTenant(ctx, slo)
+	if err != nil {
+		return err
+	}
+	if tenant == "" {
+		tenant = "fake"
+	}
+
+	status, err := syncRuler(ctx, lokiRulerClient(), slo.Status.LokiRuler, tenant, fmt.Sprintf("%s-%s", slo.Name, slo.Namespace), groups)
+	if status != nil && len(status.Groups) == 0 {
+		status = nil
+	}
+	changed := !equality.Semantic.DeepEqual(slo.Status.LokiRuler, status)
+	slo.Status.LokiRuler = status
+	return r.updateRulerStatus(ctx, slo, changed, err)
+}
+
+// lokiRulerClient returns a client for the ruler API of Grafana Loki.
+func lokiRulerClient() *rulerClient {
+	return &rulerClient{
+		address: sloOperatorLokiURL,
+		prefix:  "/loki/api/v1/rules",
+	}
+}
+
+// getTenant returns the tenant for a ServiceLevelObjective. If the tenant is
+// set in the spec of the ServiceLevelObjective it is used. Otherwise we return
+// the value of the label configured via the "SLO_OPERATOR_TENANT_LABEL"
+// environment variable from the namespace of the ServiceLevelObjective. If no
+// tenant can be found an empty string is returned.
+func (r *ServiceLevelObjectiveReconciler) getTenant(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) (string, error) {
+	if slo.Spec.Tenant != "" {
+		return slo.Spec.Tenant, nil
+	}
+
+	if sloOperatorTenantLabel == "" {
+		return "", nil
+	}
+
+	namespace := &corev1.Namespace{}
+	err := r.Get(ctx, types.NamespacedName{Name: slo.Namespace}, namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	return namespace.Labels[sloOperatorTenantLabel], nil
+}
+
+// finalizeServiceLevelObjective deletes all rules and expires all silences for
+// a ServiceLevelObjective resource, which were created outside of the
+// Kubernetes cluster and therefore can not be garbage collected.
+func (r *ServiceLevelObjectiveReconciler) finalizeServiceLevelObjective(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective) error {
+	err := deleteRuler(ctx, mimirRulerClient(), slo.Status.Ruler)
+	if err != nil {
+		return err
+	}
+
+	err = deleteRuler(ctx, lokiRulerClient(), slo.Status.LokiRuler)
+	if err != nil {
+		return err
+	}
+
+	// All silences are expired, by reconciling the silences without any
+	// maintenance window.
+	if len(slo.Status.Silences) > 0 {
+		return reconcileSilences(ctx, slo, nil)
+	}
+	return nil
+}
+
+// queryLanguage is the query language, which is used for the expressions of
+// the generated rules.
+type queryLanguage string
+
+const (
+	// promQL is the query language of Prometheus. It is used for all modes,
+	// except the "victoriametrics" mode.
+	promQL queryLanguage = "promql"
+	// metricsQL is the query language of VictoriaMetrics. It is a superset of
+	// PromQL, the generated expressions only differ in the usage of the
+	// "default" operator instead of "or vector(0)" (see orZero). No other
+	// MetricsQL functions are used, because the long windows are already
+	// derived from the recorded metrics for all query languages.
+	//
+	// See https://docs.victoriametrics.com/victoriametrics/metricsql/
+	metricsQL queryLanguage = "metricsql"
+	// logQL is the query language of Grafana Loki. It is used for SLOs with a
+	// LogQL SLI. Since the Loki ruler can not query the recorded metrics, only
+	// the recordings of the user provided queries are evaluated by Loki.
+	//
+	// See https://grafana.com/docs/loki/latest/query/
+	logQL queryLanguage = "logql"
+)
+
+// orZero returns an expression for the error query of the SLI, which returns
+// 0 when the error query doesn't return a result. In PromQL we have to use "or
+// vector(0)", while MetricsQL supports the "default" operator, which only
+// fills the gaps of the query.
+//
+// When the SLO is grouped by labels, a missing result must be filled for each
+// group of the total query, so that we use the total query multiplied with 0
+// for all query languages.
+func orZero(sli ricobergerdev1alpha1.SLI, groupBy []string, language queryLanguage) string {
+	if len(groupBy) > 0 {
+		return fmt.Sprintf("(%s) or on(%s) (%s) * 0", sli.ErrorQuery, strings.Join(groupBy, ", "), sli.TotalQuery)
+	}
+	if language == metricsQL {
+		return fmt.Sprintf("(%s) default 0", sli.ErrorQuery)
+	}
+	return fmt.Sprintf("(%s) or vector(0)", sli.ErrorQuery)
+}
+
 // DurationPointer is a helper function to parse a Duration string into a
 // *Duration.
 func DurationPointer(s string) *monitoringv1.Duration {
@@ -285,23 +866,45 @@ func DurationPointer(s string) *monitoringv1.Duration {
 //     week it is. This accounts better for traffic variation over weekends than
 //     a 30 day SLO.
 //   - "slo:objective": The user configured target objective of the SLO.
-//   - "slo:total": A recording rule of the configured total metric. This is
-//     only used for the Grafana dashboard.
-//   - "slo:errors_total: A recording rule for the configured error metric. This
-//     is only used for the Grafana dashboard.
+//   - "slo:total": A recording rule of the configured total metric for a short
+//     window of 2 minutes.
+//   - "slo:errors_total: A recording rule for the configured error metric for a
+//     short window of 2 minutes.
 //   - "slo:availability: The actual value for the SLO, calculated via the
-//     provided total and error metric. This metric can also be used to
-//     calculated the error budget via
+//     recorded total and error metric over the SLO window. Since the raw
+//     queries are not evaluated over the whole SLO window, this is a lot
+//     cheaper than evaluating the provided queries over 28 days every 30
+//     seconds. This metric can also be used to calculated the error budget via
 //     "((slo:availability - slo:objective)) / (1 - slo:objective)"
 //   - "slo:burnrate": The current burn rate for the SLO. This metric is
 //     available for multiple windows. The window is specified in the "window"
 //     label of the metric.
 //   - "SLOMetricAbsent": An alerting rule which fires when the user specified
-//     total metric is absent.
+//     total metric is absent. The checked queries can be adjusted by the user,
+//     see generatePrometheusRuleAbsentAlerting.
 //   - "SLOErrorBudgetBurn": Multiple alerting rules which are fired when the
 //     error budget is burning to fast / to statically over the SLO window, see
 //     https://sre.google/workbook/alerting-on-slos/.
-func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string]string) ([]monitoringv1.RuleGroup, error) {
+//
+// The expressions are generated for the provided query language. The PromQL
+// and MetricsQL expressions only differ in the handling of missing error
+// series, see orZero. For LogQL the
+// generic and errors groups only contain the recordings of the user provided
+// queries, while all other rules are returned in a third group for Prometheus.
+func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string]string, language queryLanguage) ([]monitoringv1.RuleGroup, error) {
+	// Composite SLOs are calculated from the recorded metrics of other SLOs
+	// instead of the SLI, so that the rules are generated separately.
+	if slo.Composite != nil {
+		return generateCompositeRuleGroup(slo, labels)
+	}
+
+	// Render the variables in the queries of the SLO, so that only the
+	// "${window}" placeholder is left in the queries.
+	slo, err := renderSLOQueries(slo, labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render queries of SLO: %w", err)
+	}
+
 	// Validate the SLO specified by the user via the ServiceLevelObjective
 	// resource. Each SLO must contain a name, objective, total query and error
 	// query. The total and error query must also contain a "${window}"
@@ -315,10 +918,23 @@ func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string
 		return nil, fmt.Errorf("SLI queries must contain the ${window} placeholder")
 	}
 
+	if err := validateObjectives(slo.Objectives); err != nil {
+		return nil, err
+	}
+
+	// If the SLO is grouped by labels, the queries must aggregate by these
+	// labels. LogQL queries can not be parsed by the operator, so that they are
+	// not validated.
+	if len(slo.GroupBy) > 0 && language != logQL {
+		if err := validateGroupBy(slo.GroupBy, slo.SLI.TotalQuery, slo.SLI.ErrorQuery); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate a unique id for each SLO. so that the resulting metrics are
 	// always having a unique label set. The id and name of the SLO are then
 	// added to the labels.
-	id := fmt.Sprintf("%s-%s-%s", labels["name"], labels["namespace"], slo.Name)
+	id := generateSLOID(labels, slo.Name)
 
 	sloLabels := make(map[string]string)
 	maps.Copy(sloLabels, labels)
@@ -338,10 +954,18 @@ func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string
 	// Generate the generic and errors Prometheus rules. The errors group
 	// contains the burn rate metrics and alerts. All other metrics and alerts
 	// are added to the generic group.
+	//
+	// The window of the SLO is a rolling window of 28 days or a calendar
+	// window, which ends at the end of the current period.
+	sloWindow, err := generateSLOWindow(slo.Window)
+	if err != nil {
+		return nil, err
+	}
+
 	genericRules := []monitoringv1.Rule{
 		{
 			Record: "slo:window",
-			Expr:   intstr.FromInt(2419200),
+			Expr:   intstr.FromInt(int(sloWindow.Seconds)),
 			Labels: sloLabels,
 		},
 		{
@@ -356,24 +980,39 @@ func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string
 		},
 		{
 			Record: "slo:errors_total",
-			Expr:   intstr.FromString(strings.ReplaceAll(fmt.Sprintf("(%s) or vector(0)", slo.SLI.ErrorQuery), "${window}", "2m")),
+			Expr:   intstr.FromString(strings.ReplaceAll(orZero(slo.SLI, slo.GroupBy, language), "${window}", "2m")),
 			Labels: sloLabels,
 		},
 		{
 			Record: "slo:availability",
-			Expr:   intstr.FromString(strings.ReplaceAll(fmt.Sprintf(`1 - ((%s) or vector(0)) / (%s)`, slo.SLI.ErrorQuery, slo.SLI.TotalQuery), "${window}", "28d")),
+			Expr:   intstr.FromString(fmt.Sprintf("1 - %s", generateRecordedErrorRatio(id, sloWindow.Range, sloWindow.Modifier))),
 			Labels: sloLabels,
 		},
 	}
 
 	errorsRules := []monitoringv1.Rule{
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "5m"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "30m"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "1h"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "2h"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "6h"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "1d"),
-		generatePrometheusRuleBurnRateRecording(slo.SLI, sloLabels, "4d"),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "5m", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "30m", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "1h", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "2h", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "6h", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "1d", language),
+		generatePrometheusRuleBurnRateRecording(slo.SLI, slo.GroupBy, id, sloLabels, "4d", language),
+	}
+
+	// For dynamic burn rates the thresholds of the alerts are scaled by the
+	// ratio of the average traffic over the SLO window to the traffic in the
+	// long window of each alert, so that we have to record the traffic ratio
+	// for these windows.
+	if strings.EqualFold(slo.Alerting.BurnRateType, "dynamic") || slices.ContainsFunc(slo.Objectives, func(o ricobergerdev1alpha1.Objective) bool {
+		return strings.EqualFold(o.Alerting.BurnRateType, "dynamic")
+	}) {
+		errorsRules = append(errorsRules, []monitoringv1.Rule{
+			generatePrometheusRuleTrafficRatioRecording(id, sloLabels, sloWindow, "1h"),
+			generatePrometheusRuleTrafficRatioRecording(id, sloLabels, sloWindow, "6h"),
+			generatePrometheusRuleTrafficRatioRecording(id, sloLabels, sloWindow, "1d"),
+			generatePrometheusRuleTrafficRatioRecording(id, sloLabels, sloWindow, "4d"),
+		}...)
 	}
 
 	// If the alerting isn't disabled by the user, we add the alerting rules
@@ -381,51 +1020,450 @@ func generatePrometheusRuleGroup(slo ricobergerdev1alpha1.SLO, labels map[string
 	// provided a list of severieties for the alerts. If not, we use a default
 	// list of severities.
 	if !slo.Alerting.Disabled {
-		severities := []string{"critical", "error", "error", "warning", "warning"}
-		if len(slo.Alerting.Severities) == 5 {
-			severities = slo.Alerting.Severities
+		severities := generateSeverities(slo.Alerting)
+
+		// The absent alert is not generated for LogQL SLIs, because the
+		// "absent" function is not available for metric queries in LogQL.
+		if language != logQL && (slo.Alerting.Absent == nil || !slo.Alerting.Absent.Disabled) {
+			absentRule, err := generatePrometheusRuleAbsentAlerting(slo.SLI, slo.Alerting.Absent, sloLabels, severities[0])
+			if err != nil {
+				return nil, err
+			}
+			genericRules = append(genericRules, absentRule)
 		}
 
-		genericRules = append(genericRules, []monitoringv1.Rule{
-			generatePrometheusRuleAbsentAlerting(slo.SLI.TotalQuery, sloLabels, severities[0]),
-		}...)
+		errorsRules = append(errorsRules, generatePrometheusRuleBurnRateAlerts(slo.Alerting, id, sloLabels, objective, severities)...)
+	}
 
-		errorsRules = append(errorsRules, []monitoringv1.Rule{
-			generatePrometheusRuleBurnRateAlerting(id, sloLabels, "5m", "1h", "14", objective, "2m", severities[1]),
-			generatePrometheusRuleBurnRateAlerting(id, sloLabels, "30m", "6h", "7", objective, "15m", severities[2]),
-			generatePrometheusRuleBurnRateAlerting(id, sloLabels, "2h", "1d", "2", objective, "1h", severities[3]),
-			generatePrometheusRuleBurnRateAlerting(id, sloLabels, "6h", "4d", "1", objective, "3h", severities[4]),
-		}...)
+	// Additional objectives are reusing the recorded metrics of the SLI, so
+	// that only the objective and the burn rate alerts are generated for each
+	// of them. The series are distinguished by the "objective" label.
+	for _, o := range slo.Objectives {
+		objectiveLabels := make(map[string]string)
+		maps.Copy(objectiveLabels, sloLabels)
+		objectiveLabels["objective"] = o.Name
+
+		value, err := strconv.ParseFloat(o.Objective, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse objective %s: %w", o.Name, err)
+		}
+		value = value / 100.0
+
+		genericRules = append(genericRules, monitoringv1.Rule{
+			Record: "slo:objective",
+			Expr:   intstr.FromString(strconv.FormatFloat(value, 'f', -1, 64)),
+			Labels: objectiveLabels,
+		})
+
+		if !o.Alerting.Disabled {
+			errorsRules = append(errorsRules, generatePrometheusRuleBurnRateAlerts(o.Alerting, id, objectiveLabels, value, generateSeverities(o.Alerting))...)
+		}
+	}
+
+	// For LogQL SLIs only the recordings of the raw queries for the short
+	// windows are evaluated by the Loki ruler, which writes them to Prometheus.
+	// All other rules are only using the recorded metrics, so that they are
+	// returned in a third group, which must be evaluated by Prometheus. This
+	// avoids running the LogQL queries over the SLO window and the long burn
+	// rate windows in Loki.
+	var recordedGroups []monitoringv1.RuleGroup
+	if language == logQL {
+		lokiRule := func(rule monitoringv1.Rule) bool {
+			return rule.Record == "slo:total" || rule.Record == "slo:errors_total" || (rule.Record == "slo:burnrate" && rule.Labels["window"] != "1d" && rule.Labels["window"] != "4d")
+		}
+
+		var lokiGenericRules, lokiErrorsRules, recordedRules []monitoringv1.Rule
+		for _, rule := range genericRules {
+			if lokiRule(rule) {
+				lokiGenericRules = append(lokiGenericRules, rule)
+			} else {
+				recordedRules = append(recordedRules, rule)
+			}
+		}
+		for _, rule := range errorsRules {
+			if lokiRule(rule) {
+				lokiErrorsRules = append(lokiErrorsRules, rule)
+			} else {
+				recordedRules = append(recordedRules, rule)
+			}
+		}
+		genericRules = lokiGenericRules
+		errorsRules = lokiErrorsRules
+
+		recordedGroup, err := generatePrometheusRuleGroupWithOptions(fmt.Sprintf("slo-recorded-%s", id), recordedRules, slo.RuleGroup)
+		if err != nil {
+			return nil, err
+		}
+		recordedGroups = append(recordedGroups, recordedGroup)
+	}
+
+	genericGroup, err := generatePrometheusRuleGroupWithOptions(fmt.Sprintf("slo-generic-%s", id), genericRules, slo.RuleGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	errorsGroup, err := generatePrometheusRuleGroupWithOptions(fmt.Sprintf("slo-errors-%s", id), errorsRules, slo.RuleGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]monitoringv1.RuleGroup{genericGroup, errorsGroup}, recordedGroups...), nil
+}
+
+// generateErrorBudgetRuleGroups generates the Prometheus rule groups with the
+// error budget recording rules for a SLO in the ServiceLevelObjective resource.
+// One group is generated for the objective of the SLO and one for each
+// additional objective.
+//
+// The rules are calculated from the "slo:availability", "slo:total",
+// "slo:errors_total" and "slo:burnrate" metrics, which are recorded by the
+// groups generated via the generatePrometheusRuleGroup function:
+//   - "slo:error_budget_remaining": The ratio of the error budget, which is
+//     remaining in the SLO window. The value is negative, when the error
+//     budget is exhausted.
+//   - "slo:error_budget_consumed": The ratio of the error budget, which was
+//     consumed in the SLO window.
+//   - "slo:error_budget_remaining_events": The number of error events, which
+//     are remaining in the SLO window. This assumes that the total and error
+//     queries are returning the number of events per second, e.g. via "rate".
+//   - "slo:error_budget_exhaustion_seconds": The time in seconds until the
+//     error budget is exhausted with the current burn rate of the 1h window.
+//   - "slo:error_budget_forecast": The forecasted remaining error budget at
+//     the end of the forecast horizon, based on the linear trend of the last
+//     day.
+//   - "slo:error_budget_exhaustion_timestamp": The forecasted unix timestamp,
+//     at which the error budget is exhausted, based on the linear trend of the
+//     last day. The metric is only available, when the error budget is
+//     decreasing.
+//   - "SLOErrorBudgetLow" / "SLOErrorBudgetExhausted": Alerting rules for the
+//     user provided thresholds of the remaining error budget.
+//   - "SLOErrorBudgetForecast": An alerting rule, which fires when the error
+//     budget is forecasted to be exhausted within the forecast horizon.
+func generateErrorBudgetRuleGroups(slo ricobergerdev1alpha1.SLO, labels map[string]string) ([]monitoringv1.RuleGroup, error) {
+	objectives := append([]ricobergerdev1alpha1.Objective{{Objective: slo.Objective, Alerting: slo.Alerting}}, slo.Objectives...)
+
+	var groups []monitoringv1.RuleGroup
+	for _, objective := range objectives {
+		group, err := generateErrorBudgetRuleGroup(slo, objective, labels)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// generateErrorBudgetRuleGroup generates the error budget rule group for a
+// single objective of a SLO. The objective of the SLO itself has no name. When
+// the SLO has additional objectives, the selectors for the error budget
+// metrics contain the "objective" label, so that the series of the different
+// objectives are not mixed up.
+func generateErrorBudgetRuleGroup(slo ricobergerdev1alpha1.SLO, o ricobergerdev1alpha1.Objective, labels map[string]string) (monitoringv1.RuleGroup, error) {
+	id := generateSLOID(labels, slo.Name)
+
+	sloLabels := make(map[string]string)
+	maps.Copy(sloLabels, labels)
+	sloLabels["id"] = id
+	sloLabels["slo"] = slo.Name
+
+	groupName := fmt.Sprintf("slo-budget-%s", id)
+	selector := fmt.Sprintf(`id="%s"`, id)
+	ignoring := "window"
+	if len(slo.Objectives) > 0 {
+		selector = fmt.Sprintf(`id="%s", objective="%s"`, id, o.Name)
+	}
+	if o.Name != "" {
+		sloLabels["objective"] = o.Name
+		groupName = fmt.Sprintf("slo-budget-%s-%s", id, o.Name)
+		ignoring = "window, objective"
+	}
+
+	objective, err := strconv.ParseFloat(o.Objective, 64)
+	if err != nil {
+		return monitoringv1.RuleGroup{}, fmt.Errorf("failed to parse SLO objective: %w", err)
+	}
+	objectiveStr := strconv.FormatFloat(objective/100.0, 'f', -1, 64)
+	errorBudget := fmt.Sprintf("(1-%s)", objectiveStr)
+
+	sloWindow, err := generateSLOWindow(slo.Window)
+	if err != nil {
+		return monitoringv1.RuleGroup{}, err
+	}
+
+	rules := []monitoringv1.Rule{
+		{
+			Record: "slo:error_budget_remaining",
+			Expr:   intstr.FromString(fmt.Sprintf(`(slo:availability{id="%s"} - %s) / %s`, id, objectiveStr, errorBudget)),
+			Labels: sloLabels,
+		},
+		{
+			Record: "slo:error_budget_consumed",
+			Expr:   intstr.FromString(fmt.Sprintf(`(1 - slo:availability{id="%s"}) / %s`, id, errorBudget)),
+			Labels: sloLabels,
+		},
+	}
+
+	// Composite SLOs do not record the "slo:total" and "slo:errors_total"
+	// metrics, so that the remaining events can not be calculated. The
+	// ratio-only presets are recording a gauge instead of events, so that the
+	// number of events would be meaningless.
+	//
+	// For calendar windows the allowed errors are extrapolated to the whole
+	// period, while the errors are only counted for the elapsed part of the
+	// period, because the average is only calculated over the samples of the
+	// current period.
+	if slo.Composite == nil && !isRatioOnlySLIPreset(slo.SLI.Preset) {
+		expr := fmt.Sprintf(`(%s * avg_over_time(slo:total{id="%s"}[%s]%s) - avg_over_time(slo:errors_total{id="%s"}[%s]%s)) * %d`, errorBudget, id, sloWindow.Range, sloWindow.Modifier, id, sloWindow.Range, sloWindow.Modifier, sloWindow.Seconds)
+		if sloWindow.IsCalendar() {
+			expr = fmt.Sprintf(`%s * avg_over_time(slo:total{id="%s"}[%s]%s) * %d - avg_over_time(slo:errors_total{id="%s"}[%s]%s) * (time() - %d)`, errorBudget, id, sloWindow.Range, sloWindow.Modifier, sloWindow.Seconds, id, sloWindow.Range, sloWindow.Modifier, sloWindow.Start.Unix())
+		}
+
+		rules = append(rules, monitoringv1.Rule{
+			Record: "slo:error_budget_remaining_events",
+			Expr:   intstr.FromString(expr),
+			Labels: sloLabels,
+		})
+	}
+
+	rules = append(rules, monitoringv1.Rule{
+		Record: "slo:error_budget_exhaustion_seconds",
+		Expr:   intstr.FromString(fmt.Sprintf(`clamp_min(slo:error_budget_remaining{%s}, 0) * %d * %s / ignoring(%s) slo:burnrate{window="1h", id="%s"}`, selector, sloWindow.Seconds, errorBudget, ignoring, id)),
+		Labels: sloLabels,
+	})
+
+	// The forecast horizon can be configured by the user, if the forecast
+	// alert is enabled. The horizon is also used for the forecast recording
+	// rule, so that the alert can use the recorded metric.
+	horizon := "7d"
+	if o.Alerting.Forecast != nil && o.Alerting.Forecast.Horizon != "" {
+		horizon = o.Alerting.Forecast.Horizon
+	}
+	horizonDuration, err := model.ParseDuration(horizon)
+	if err != nil {
+		return monitoringv1.RuleGroup{}, fmt.Errorf("failed to parse forecast horizon: %w", err)
 	}
 
-	return []monitoringv1.RuleGroup{
+	rules = append(rules, []monitoringv1.Rule{
 		{
-			Name:     fmt.Sprintf("slo-generic-%s", id),
-			Interval: DurationPointer("30s"),
-			Rules:    genericRules,
+			Record: "slo:error_budget_forecast",
+			Expr:   intstr.FromString(fmt.Sprintf(`predict_linear(slo:error_budget_remaining{%s}[1d], %d)`, selector, int64(time.Duration(horizonDuration).Seconds()))),
+			Labels: sloLabels,
 		},
 		{
-			Name:     fmt.Sprintf("slo-errors-%s", id),
-			Interval: DurationPointer("30s"),
-			Rules:    errorsRules,
+			Record: "slo:error_budget_exhaustion_timestamp",
+			Expr:   intstr.FromString(fmt.Sprintf(`time() + clamp_min(slo:error_budget_remaining{%s}, 0) / -(deriv(slo:error_budget_remaining{%s}[1d]) < 0)`, selector, selector)),
+			Labels: sloLabels,
 		},
+	}...)
+
+	if !o.Alerting.Disabled {
+		for _, threshold := range o.Alerting.ErrorBudgetThresholds {
+			rule, err := generatePrometheusRuleErrorBudgetAlerting(selector, sloLabels, threshold)
+			if err != nil {
+				return monitoringv1.RuleGroup{}, err
+			}
+			rules = append(rules, rule)
+		}
+
+		if o.Alerting.Forecast != nil {
+			severity := o.Alerting.Forecast.Severity
+			if severity == "" {
+				severity = "warning"
+			}
+
+			alertLabels := make(map[string]string)
+			maps.Copy(alertLabels, sloLabels)
+			alertLabels["severity"] = severity
+
+			rules = append(rules, monitoringv1.Rule{
+				Alert:  "SLOErrorBudgetForecast",
+				Expr:   intstr.FromString(fmt.Sprintf(`slo:error_budget_forecast{%s} < 0`, selector)),
+				For:    DurationPointer("1h"),
+				Labels: alertLabels,
+			})
+		}
+	}
+
+	return generatePrometheusRuleGroupWithOptions(groupName, rules, slo.RuleGroup)
+}
+
+// generatePrometheusRuleErrorBudgetAlerting generates a single Prometheus alert
+// rule, which fires when the remaining error budget is below the provided
+// threshold. For a threshold of 0 the alert is named "SLOErrorBudgetExhausted"
+// and fires when no error budget is remaining. For all other thresholds the
+// alert is named "SLOErrorBudgetLow". The threshold is added as label to the
+// alert, so that the alerts for different thresholds can be distinguished.
+func generatePrometheusRuleErrorBudgetAlerting(selector string, labels map[string]string, threshold ricobergerdev1alpha1.ErrorBudgetThreshold) (monitoringv1.Rule, error) {
+	remaining, err := strconv.ParseFloat(threshold.Remaining, 64)
+	if err != nil {
+		return monitoringv1.Rule{}, fmt.Errorf("failed to parse error budget threshold: %w", err)
+	}
+	if remaining < 0 || remaining > 100 {
+		return monitoringv1.Rule{}, fmt.Errorf("error budget threshold must be between 0 and 100")
+	}
+
+	alertLabels := make(map[string]string)
+	maps.Copy(alertLabels, labels)
+	alertLabels["severity"] = threshold.Severity
+	alertLabels["threshold"] = threshold.Remaining
+
+	if remaining == 0 {
+		return monitoringv1.Rule{
+			Alert:  "SLOErrorBudgetExhausted",
+			Expr:   intstr.FromString(fmt.Sprintf(`slo:error_budget_remaining{%s} <= 0`, selector)),
+			For:    DurationPointer("5m"),
+			Labels: alertLabels,
+		}, nil
+	}
+
+	return monitoringv1.Rule{
+		Alert:  "SLOErrorBudgetLow",
+		Expr:   intstr.FromString(fmt.Sprintf(`slo:error_budget_remaining{%s} < %s`, selector, strconv.FormatFloat(remaining/100.0, 'f', -1, 64))),
+		For:    DurationPointer("5m"),
+		Labels: alertLabels,
 	}, nil
 }
 
+// generateSLOID returns the unique id of a SLO, which is generated from the
+// name and namespace of the ServiceLevelObjective and the name of the SLO.
+func generateSLOID(labels map[string]string, name string) string {
+	return fmt.Sprintf("%s-%s-%s", labels["name"], labels["namespace"], name)
+}
+
+// validateObjectives validates the additional objectives of a SLO. Each
+// objective must have a unique name, because the name is used as value for
+// the "objective" label.
+func validateObjectives(objectives []ricobergerdev1alpha1.Objective) error {
+	names := make(map[string]bool)
+	for _, o := range objectives {
+		if o.Name == "" || o.Objective == "" {
+			return fmt.Errorf("required field name or objective of objective is missing")
+		}
+		if names[o.Name] {
+			return fmt.Errorf("objective %s is defined multiple times", o.Name)
+		}
+		names[o.Name] = true
+	}
+
+	return nil
+}
+
+// generateSeverities returns the severities for the absent alert and the burn
+// rate alerts. If the user didn't provide a list of 5 severities, the default
+// list of severities is used.
+func generateSeverities(alerting ricobergerdev1alpha1.Alerting) []string {
+	if len(alerting.Severities) == 5 {
+		return alerting.Severities
+	}
+	return []string{"critical", "error", "error", "warning", "warning"}
+}
+
+// generatePrometheusRuleGroupWithOptions generates a Prometheus rule group with
+// the provided name and rules. The evaluation options of the group are set
+// based on the user provided options. If the user didn't provide an interval,
+// the default interval of 30 seconds is used.
+func generatePrometheusRuleGroupWithOptions(name string, rules []monitoringv1.Rule, options ricobergerdev1alpha1.RuleGroup) (monitoringv1.RuleGroup, error) {
+	group := monitoringv1.RuleGroup{
+		Name:                    name,
+		Interval:                DurationPointer("30s"),
+		Rules:                   rules,
+		PartialResponseStrategy: options.PartialResponseStrategy,
+	}
+
+	if options.Interval != "" {
+		if _, err := model.ParseDuration(options.Interval); err != nil {
+			return group, fmt.Errorf("failed to parse rule group interval: %w", err)
+		}
+		group.Interval = DurationPointer(options.Interval)
+	}
+
+	if options.QueryOffset != "" {
+		if _, err := model.ParseDuration(options.QueryOffset); err != nil {
+			return group, fmt.Errorf("failed to parse rule group query offset: %w", err)
+		}
+		group.QueryOffset = DurationPointer(options.QueryOffset)
+	}
+
+	if len(options.Labels) > 0 {
+		group.Labels = maps.Clone(options.Labels)
+	}
+
+	if options.Limit > 0 {
+		group.Limit = &options.Limit
+	}
+
+	if options.VictoriaMetrics.EvalOffset != "" {
+		if _, err := model.ParseDuration(options.VictoriaMetrics.EvalOffset); err != nil {
+			return group, fmt.Errorf("failed to parse rule group eval offset: %w", err)
+		}
+	}
+
+	return group, nil
+}
+
+// generateRecordedErrorRatio returns an expression, which calculates the ratio
+// of errors for the provided window from the recorded "slo:errors_total" and
+// "slo:total" metrics of the SLO with the provided id.
+//
+// Since both metrics are recorded with the same interval, the sum of all
+// samples in the window can be used to calculate the ratio, without evaluating
+// the user provided queries over the whole window. The modifier is appended to
+// the range selectors and is used for calendar windows, see sloWindow.
+func generateRecordedErrorRatio(id, window, modifier string) string {
+	return fmt.Sprintf(`sum_over_time(slo:errors_total{id="%s"}[%s]%s) / sum_over_time(slo:total{id="%s"}[%s]%s)`, id, window, modifier, id, window, modifier)
+}
+
 // generatePrometheusRuleAbsentAlerting generates a sinlge Prometheus alert
 // rule, which is used to alert with the provided severity, when the provided
 // metric is absent.
-func generatePrometheusRuleAbsentAlerting(query string, labels map[string]string, severity string) monitoringv1.Rule {
+//
+// By default the total query of the SLI is checked. The user can provide an
+// own query or a list of metric selectors instead, which is useful for SLIs
+// built from several metrics, where the absent function for the aggregated
+// query rarely fires. The error query can be checked in addition. All checked
+// queries are combined via the "or" operator, so that the alert fires when one
+// of them is absent.
+func generatePrometheusRuleAbsentAlerting(sli ricobergerdev1alpha1.SLI, absent *ricobergerdev1alpha1.AbsentAlerting, labels map[string]string, severity string) (monitoringv1.Rule, error) {
+	forDuration := "10m"
+	queries := []string{sli.TotalQuery}
+
+	if absent != nil {
+		if absent.For != "" {
+			if _, err := model.ParseDuration(absent.For); err != nil {
+				return monitoringv1.Rule{}, fmt.Errorf("failed to parse absent alert duration: %w", err)
+			}
+			forDuration = absent.For
+		}
+		if absent.Severity != "" {
+			severity = absent.Severity
+		}
+
+		if len(absent.Selectors) > 0 {
+			queries = absent.Selectors
+		} else if absent.Query != "" {
+			queries = []string{absent.Query}
+		}
+		if absent.ErrorQuery {
+			queries = append(slices.Clone(queries), sli.ErrorQuery)
+		}
+	}
+
 	alertLabels := make(map[string]string)
 	maps.Copy(alertLabels, labels)
 	alertLabels["severity"] = severity
 
+	exprs := make([]string, 0, len(queries))
+	for _, query := range queries {
+		exprs = append(exprs, fmt.Sprintf("absent(%s) == 1", query))
+	}
+
 	return monitoringv1.Rule{
 		Alert:  "SLOMetricAbsent",
-		Expr:   intstr.FromString(strings.ReplaceAll(fmt.Sprintf("absent(%s) == 1", query), "${window}", "2m")),
-		For:    DurationPointer("10m"),
+		Expr:   intstr.FromString(strings.ReplaceAll(strings.Join(exprs, " or "), "${window}", "2m")),
+		For:    DurationPointer(forDuration),
 		Labels: alertLabels,
-	}
+	}, nil
 }
 
 // generatePrometheusRuleBurnRateRecording generates a single Prometheus
@@ -434,11 +1472,31 @@ func generatePrometheusRuleAbsentAlerting(query string, labels map[string]string
 // The recording rule is named "slo:burnrate" and contains the specified window
 // as label. The burn rate is calculated by dividing the error metric by the
 // total metric and replacing the "${window}" placeholder within the metric.
-func generatePrometheusRuleBurnRateRecording(sli ricobergerdev1alpha1.SLI, labels map[string]string, window string) monitoringv1.Rule {
+//
+// The burn rates for the long windows (1d and 4d) are calculated from the
+// recorded "slo:errors_total" and "slo:total" metrics. For MetricsQL missing
+// errors are handled via the "default" operator.
+func generatePrometheusRuleBurnRateRecording(sli ricobergerdev1alpha1.SLI, groupBy []string, id string, labels map[string]string, window string, language queryLanguage) monitoringv1.Rule {
 	recordLabels := make(map[string]string)
 	maps.Copy(recordLabels, labels)
 	recordLabels["window"] = window
 
+	if window == "1d" || window == "4d" {
+		return monitoringv1.Rule{
+			Record: "slo:burnrate",
+			Expr:   intstr.FromString(generateRecordedErrorRatio(id, window, "")),
+			Labels: recordLabels,
+		}
+	}
+
+	if language == metricsQL {
+		return monitoringv1.Rule{
+			Record: "slo:burnrate",
+			Expr:   intstr.FromString(strings.ReplaceAll(fmt.Sprintf("(%s) / (%s)", orZero(sli, groupBy, language), sli.TotalQuery), "${window}", window)),
+			Labels: recordLabels,
+		}
+	}
+
 	return monitoringv1.Rule{
 		Record: "slo:burnrate",
 		Expr:   intstr.FromString(strings.ReplaceAll(fmt.Sprintf("(%s) / (%s)", sli.ErrorQuery, sli.TotalQuery), "${window}", window)),
@@ -446,24 +1504,105 @@ func generatePrometheusRuleBurnRateRecording(sli ricobergerdev1alpha1.SLI, label
 	}
 }
 
+// generatePrometheusRuleTrafficRatioRecording generates a Prometheus recording
+// rule for the ratio of the average traffic over the SLO window to the average
+// traffic in the provided window. The ratio is used to scale the thresholds of
+// the burn rate alerts, when dynamic burn rates are used. For calendar windows
+// the average traffic is calculated over the current period.
+func generatePrometheusRuleTrafficRatioRecording(id string, labels map[string]string, w sloWindow, window string) monitoringv1.Rule {
+	recordLabels := make(map[string]string)
+	maps.Copy(recordLabels, labels)
+	recordLabels["window"] = window
+
+	return monitoringv1.Rule{
+		Record: "slo:traffic_ratio",
+		Expr:   intstr.FromString(fmt.Sprintf(`avg_over_time(slo:total{id="%s"}[%s]%s) / avg_over_time(slo:total{id="%s"}[%s])`, id, w.Range, w.Modifier, id, window)),
+		Labels: recordLabels,
+	}
+}
+
+// generatePrometheusRuleBurnRateAlerts generates the four multiwindow,
+// multi-burn-rate alerts for the provided objective. The first severity is
+// used for the absent alert, so that the alerts are using the remaining 4
+// severities ordered by criticality.
+func generatePrometheusRuleBurnRateAlerts(alerting ricobergerdev1alpha1.Alerting, id string, labels map[string]string, objective float64, severities []string) []monitoringv1.Rule {
+	return []monitoringv1.Rule{
+		generatePrometheusRuleBurnRateAlerting(alerting, id, labels, "5m", "1h", "14", objective, "2m", severities[1]),
+		generatePrometheusRuleBurnRateAlerting(alerting, id, labels, "30m", "6h", "7", objective, "15m", severities[2]),
+		generatePrometheusRuleBurnRateAlerting(alerting, id, labels, "2h", "1d", "2", objective, "1h", severities[3]),
+		generatePrometheusRuleBurnRateAlerting(alerting, id, labels, "6h", "4d", "1", objective, "3h", severities[4]),
+	}
+}
+
 // generatePrometheusRuleBurnRateAlerting generates a single Prometheus alert
 // rule for the specified burn rates.
 //
 // This function generates an alert that fires when burn rates for two different
-// time windows both exceed. The alert is named "SLOErrorBudgetBurn".
-func generatePrometheusRuleBurnRateAlerting(id string, labels map[string]string, burnrate1 string, burnrate2 string, factor string, objective float64, forDuration string, severity string) monitoringv1.Rule {
+// time windows both exceed. The alert is named "SLOErrorBudgetBurn". Since the
+// recorded burn rates are only differing in the "window" label, the label must
+// be ignored when both burn rates are combined. The alert contains the window
+// of the first burn rate in the "window" label.
+//
+// To protect services with a low traffic against alerts caused by a single
+// failed event, the user can configure a minimum number of events in the short
+// window, which is required to fire the alert, and traffic-scaled thresholds.
+// With traffic-scaled thresholds the threshold of each window is at least the
+// error ratio of a single failed event in the window.
+//
+// When dynamic burn rates are used, the static threshold is multiplied with
+// the recorded traffic ratio of the long window, see
+// generatePrometheusRuleTrafficRatioRecording.
+func generatePrometheusRuleBurnRateAlerting(alerting ricobergerdev1alpha1.Alerting, id string, labels map[string]string, burnrate1 string, burnrate2 string, factor string, objective float64, forDuration string, severity string) monitoringv1.Rule {
 	alertLabels := make(map[string]string)
 	maps.Copy(alertLabels, labels)
 	alertLabels["severity"] = severity
 
+	burnrate := func(window string) string {
+		return fmt.Sprintf(`slo:burnrate{window="%s", id="%s"}`, window, id)
+	}
+
+	operator := "and ignoring(window)"
+
+	threshold := func(window string) string {
+		if strings.EqualFold(alerting.BurnRateType, "dynamic") {
+			dynamicThreshold := fmt.Sprintf(`> ignoring(window) (%s * (1-%s)) * slo:traffic_ratio{window="%s", id="%s"}`, factor, strconv.FormatFloat(objective, 'f', -1, 64), burnrate2, id)
+			if alerting.TrafficScaledThresholds {
+				return fmt.Sprintf("%s %s %s > ignoring(window) 1 / (%s)", dynamicThreshold, operator, burnrate(window), generateTotalEvents(id, window))
+			}
+			return dynamicThreshold
+		}
+
+		if alerting.TrafficScaledThresholds {
+			return fmt.Sprintf("> ignoring(window) clamp_min(1 / (%s), %s * (1-%s))", generateTotalEvents(id, window), factor, strconv.FormatFloat(objective, 'f', -1, 64))
+		}
+		return fmt.Sprintf("> (%s * (1-%s))", factor, strconv.FormatFloat(objective, 'f', -1, 64))
+	}
+
+	expr := fmt.Sprintf(`%s %s %s %s %s`, burnrate(burnrate1), threshold(burnrate1), operator, burnrate(burnrate2), threshold(burnrate2))
+	if alerting.MinimumEvents > 0 {
+		expr = fmt.Sprintf(`%s %s %s > %d`, expr, operator, generateTotalEvents(id, burnrate1), alerting.MinimumEvents)
+	}
+
 	return monitoringv1.Rule{
 		Alert:  "SLOErrorBudgetBurn",
-		Expr:   intstr.FromString(fmt.Sprintf(`slo:burnrate{window="%s", id="%s"} > (%s * (1-%s)) and slo:burnrate{window="%s", id="%s"} > (%s * (1-%s))`, burnrate1, id, factor, strconv.FormatFloat(objective, 'f', -1, 64), burnrate2, id, factor, strconv.FormatFloat(objective, 'f', -1, 64))),
+		Expr:   intstr.FromString(expr),
 		For:    DurationPointer(forDuration),
 		Labels: alertLabels,
 	}
 }
 
+// generateTotalEvents returns an expression for the total number of events of
+// a SLO in the provided window. The expression assumes that the total query
+// returns the number of events per second, e.g. via "rate", so that the
+// average of the recorded "slo:total" metric is multiplied with the length of
+// the window.
+func generateTotalEvents(id string, window string) string {
+	duration, _ := model.ParseDuration(window)
+	seconds := int64(time.Duration(duration).Seconds())
+
+	return fmt.Sprintf(`avg_over_time(slo:total{id="%s"}[%s]) * %d`, id, window, seconds)
+}
+
 // updateConditions updates the conditions of the ServiceLevelObjective
 // resource. If the "reconcileError" is not nil, the condition will be set to
 // "Failed" with the error as message. Otherwise the condition will be set to
@@ -498,19 +1637,29 @@ func (r *ServiceLevelObjectiveReconciler) updateConditions(ctx context.Context,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ServiceLevelObjectiveReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ricobergerdev1alpha1.ServiceLevelObjective{}, serviceLevelIndicatorRefField, indexServiceLevelIndicatorRefs); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ricobergerdev1alpha1.ServiceLevelObjective{}, compositeServiceLevelObjectiveRefField, indexCompositeServiceLevelObjectiveRefs); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&ricobergerdev1alpha1.ServiceLevelObjective{}).
+		Watches(&ricobergerdev1alpha1.ServiceLevelIndicator{}, handler.EnqueueRequestsFromMapFunc(r.findServiceLevelObjectivesForServiceLevelIndicator)).
+		Watches(&ricobergerdev1alpha1.ServiceLevelObjective{}, handler.EnqueueRequestsFromMapFunc(r.findCompositeServiceLevelObjectivesForServiceLevelObjective)).
 		WithEventFilter(ignorePredicate()).
 		Named("servicelevelobjective").
 		Complete(r)
 }
 
 // ignorePredicate is used to ignore updates to CR status in which case
-// metadata.Generation does not change.
+// metadata.Generation does not change. Updates for CRs which are marked for
+// deletion are not ignored, so that we can run our finalizer.
 func ignorePredicate() predicate.Predicate {
 	return predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration()
+			return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() || !e.ObjectNew.GetDeletionTimestamp().IsZero()
 		},
 	}
 }
@@ -2,6 +2,9 @@ package controller
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 
 	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
 
@@ -9,11 +12,16 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 )
 
 var _ = Describe("ServiceLevelObjective Controller", func() {
@@ -132,7 +140,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:availability",
-								Expr:   intstr.FromString(`1 - ((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[28d]))) or vector(0)) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[28d])))`),
+								Expr:   intstr.FromString(`1 - sum_over_time(slo:errors_total{id="test-default-availability"}[28d]) / sum_over_time(slo:total{id="test-default-availability"}[28d])`),
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -222,7 +230,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   intstr.FromString(`(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[1d]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[1d])))`),
+								Expr:   intstr.FromString(`sum_over_time(slo:errors_total{id="test-default-availability"}[1d]) / sum_over_time(slo:total{id="test-default-availability"}[1d])`),
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -234,7 +242,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   intstr.FromString(`(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[4d]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[4d])))`),
+								Expr:   intstr.FromString(`sum_over_time(slo:errors_total{id="test-default-availability"}[4d]) / sum_over_time(slo:total{id="test-default-availability"}[4d])`),
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -246,7 +254,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  intstr.FromString(`slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.9)) and slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.9))`),
+								Expr:  intstr.FromString(`slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.9)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.9))`),
 								For:   DurationPointer("2m"),
 								Labels: map[string]string{
 									"namespace": "default",
@@ -259,7 +267,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  intstr.FromString(`slo:burnrate{window="30m", id="test-default-availability"} > (7 * (1-0.9)) and slo:burnrate{window="6h", id="test-default-availability"} > (7 * (1-0.9))`),
+								Expr:  intstr.FromString(`slo:burnrate{window="30m", id="test-default-availability"} > (7 * (1-0.9)) and ignoring(window) slo:burnrate{window="6h", id="test-default-availability"} > (7 * (1-0.9))`),
 								For:   DurationPointer("15m"),
 								Labels: map[string]string{
 									"namespace": "default",
@@ -272,7 +280,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  intstr.FromString(`slo:burnrate{window="2h", id="test-default-availability"} > (2 * (1-0.9)) and slo:burnrate{window="1d", id="test-default-availability"} > (2 * (1-0.9))`),
+								Expr:  intstr.FromString(`slo:burnrate{window="2h", id="test-default-availability"} > (2 * (1-0.9)) and ignoring(window) slo:burnrate{window="1d", id="test-default-availability"} > (2 * (1-0.9))`),
 								For:   DurationPointer("1h"),
 								Labels: map[string]string{
 									"namespace": "default",
@@ -285,7 +293,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  intstr.FromString(`slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.9)) and slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.9))`),
+								Expr:  intstr.FromString(`slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.9)) and ignoring(window) slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.9))`),
 								For:   DurationPointer("3h"),
 								Labels: map[string]string{
 									"namespace": "default",
@@ -298,6 +306,78 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 						},
 					},
+					{
+						Name:     "slo-budget-test-default-availability",
+						Interval: DurationPointer("30s"),
+						Rules: []monitoringv1.Rule{
+							{
+								Record: "slo:error_budget_remaining",
+								Expr:   intstr.FromString(`(slo:availability{id="test-default-availability"} - 0.9) / (1-0.9)`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_consumed",
+								Expr:   intstr.FromString(`(1 - slo:availability{id="test-default-availability"}) / (1-0.9)`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_remaining_events",
+								Expr:   intstr.FromString(`((1-0.9) * avg_over_time(slo:total{id="test-default-availability"}[28d]) - avg_over_time(slo:errors_total{id="test-default-availability"}[28d])) * 2419200`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_exhaustion_seconds",
+								Expr:   intstr.FromString(`clamp_min(slo:error_budget_remaining{id="test-default-availability"}, 0) * 2419200 * (1-0.9) / ignoring(window) slo:burnrate{window="1h", id="test-default-availability"}`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_forecast",
+								Expr:   intstr.FromString(`predict_linear(slo:error_budget_remaining{id="test-default-availability"}[1d], 604800)`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_exhaustion_timestamp",
+								Expr:   intstr.FromString(`time() + clamp_min(slo:error_budget_remaining{id="test-default-availability"}, 0) / -(deriv(slo:error_budget_remaining{id="test-default-availability"}[1d]) < 0)`),
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+						},
+					},
 				},
 			}))
 		})
@@ -361,7 +441,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:errors_total",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[2m]))) or vector(0)`,
+								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[2m]))) default 0`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -372,7 +452,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:availability",
-								Expr:   `1 - ((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[28d]))) or vector(0)) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[28d])))`,
+								Expr:   `1 - sum_over_time(slo:errors_total{id="test-default-availability"}[28d]) / sum_over_time(slo:total{id="test-default-availability"}[28d])`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -402,7 +482,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 						Rules: []vmv1beta1.Rule{
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[5m]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[5m])))`,
+								Expr:   `((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[5m]))) default 0) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[5m])))`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -414,7 +494,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[30m]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[30m])))`,
+								Expr:   `((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[30m]))) default 0) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[30m])))`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -426,7 +506,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[1h]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[1h])))`,
+								Expr:   `((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[1h]))) default 0) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[1h])))`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -438,7 +518,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[2h]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[2h])))`,
+								Expr:   `((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[2h]))) default 0) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[2h])))`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -450,7 +530,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[6h]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[6h])))`,
+								Expr:   `((sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[6h]))) default 0) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[6h])))`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -462,7 +542,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[1d]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[1d])))`,
+								Expr:   `sum_over_time(slo:errors_total{id="test-default-availability"}[1d]) / sum_over_time(slo:total{id="test-default-availability"}[1d])`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -474,7 +554,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Record: "slo:burnrate",
-								Expr:   `(sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana",response_code=~"5.*"}[4d]))) / (sum(rate(istio_requests_total{destination_workload_namespace=~"monitoring",destination_workload=~"grafana"}[4d])))`,
+								Expr:   `sum_over_time(slo:errors_total{id="test-default-availability"}[4d]) / sum_over_time(slo:total{id="test-default-availability"}[4d])`,
 								Labels: map[string]string{
 									"namespace": "default",
 									"name":      "test",
@@ -486,7 +566,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  `slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.9)) and slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.9))`,
+								Expr:  `slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.9)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.9))`,
 								For:   "2m",
 								Labels: map[string]string{
 									"namespace": "default",
@@ -499,7 +579,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  `slo:burnrate{window="30m", id="test-default-availability"} > (7 * (1-0.9)) and slo:burnrate{window="6h", id="test-default-availability"} > (7 * (1-0.9))`,
+								Expr:  `slo:burnrate{window="30m", id="test-default-availability"} > (7 * (1-0.9)) and ignoring(window) slo:burnrate{window="6h", id="test-default-availability"} > (7 * (1-0.9))`,
 								For:   "15m",
 								Labels: map[string]string{
 									"namespace": "default",
@@ -512,7 +592,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  `slo:burnrate{window="2h", id="test-default-availability"} > (2 * (1-0.9)) and slo:burnrate{window="1d", id="test-default-availability"} > (2 * (1-0.9))`,
+								Expr:  `slo:burnrate{window="2h", id="test-default-availability"} > (2 * (1-0.9)) and ignoring(window) slo:burnrate{window="1d", id="test-default-availability"} > (2 * (1-0.9))`,
 								For:   "1h",
 								Labels: map[string]string{
 									"namespace": "default",
@@ -525,7 +605,7 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 							{
 								Alert: "SLOErrorBudgetBurn",
-								Expr:  `slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.9)) and slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.9))`,
+								Expr:  `slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.9)) and ignoring(window) slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.9))`,
 								For:   "3h",
 								Labels: map[string]string{
 									"namespace": "default",
@@ -538,8 +618,903 @@ var _ = Describe("ServiceLevelObjective Controller", func() {
 							},
 						},
 					},
+					{
+						Name:     "slo-budget-test-default-availability",
+						Interval: "30s",
+						Rules: []vmv1beta1.Rule{
+							{
+								Record: "slo:error_budget_remaining",
+								Expr:   `(slo:availability{id="test-default-availability"} - 0.9) / (1-0.9)`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_consumed",
+								Expr:   `(1 - slo:availability{id="test-default-availability"}) / (1-0.9)`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_remaining_events",
+								Expr:   `((1-0.9) * avg_over_time(slo:total{id="test-default-availability"}[28d]) - avg_over_time(slo:errors_total{id="test-default-availability"}[28d])) * 2419200`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_exhaustion_seconds",
+								Expr:   `clamp_min(slo:error_budget_remaining{id="test-default-availability"}, 0) * 2419200 * (1-0.9) / ignoring(window) slo:burnrate{window="1h", id="test-default-availability"}`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_forecast",
+								Expr:   `predict_linear(slo:error_budget_remaining{id="test-default-availability"}[1d], 604800)`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+							{
+								Record: "slo:error_budget_exhaustion_timestamp",
+								Expr:   `time() + clamp_min(slo:error_budget_remaining{id="test-default-availability"}, 0) / -(deriv(slo:error_budget_remaining{id="test-default-availability"}[1d]) < 0)`,
+								Labels: map[string]string{
+									"namespace": "default",
+									"name":      "test",
+									"team":      "myteam",
+									"id":        "test-default-availability",
+									"slo":       "availability",
+								},
+							},
+						},
+					},
 				},
 			}))
 		})
+
+		It("Should successfully reconcile the resource (ConfigMap)", func() {
+			sloOperatorMode = "configmap"
+			DeferCleanup(func() { sloOperatorMode = "" })
+
+			var reloads atomic.Int32
+			prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && r.URL.Path == "/-/reload" {
+					reloads.Add(1)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(prometheus.Close)
+
+			sloOperatorPrometheusReloadURL = prometheus.URL
+			DeferCleanup(func() { sloOperatorPrometheusReloadURL = "" })
+
+			By("Reconciling the created resource")
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Check if ConfigMap was created")
+			configMap := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, typeNamespacedName, configMap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Labels).To(HaveKeyWithValue("slo-operator.ricoberger.de/rules", "true"))
+			Expect(configMap.Data).To(HaveKey("default-test.yaml"))
+
+			var ruleFile monitoringv1.PrometheusRuleSpec
+			Expect(yaml.Unmarshal([]byte(configMap.Data["default-test.yaml"]), &ruleFile)).To(Succeed())
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			groups, err := generatePrometheusRuleGroup(resource.Spec.SLOs[0], map[string]string{"name": "test", "namespace": "default", "team": "myteam"}, promQL)
+			Expect(err).NotTo(HaveOccurred())
+			budgetGroups, err := generateErrorBudgetRuleGroups(resource.Spec.SLOs[0], map[string]string{"name": "test", "namespace": "default", "team": "myteam"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ruleFile.Groups).To(Equal(append(groups, budgetGroups...)))
+
+			By("Check if the ConfigMap is not updated, when the rules didn't change")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			unchanged := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, unchanged)).To(Succeed())
+			Expect(unchanged.ResourceVersion).To(Equal(configMap.ResourceVersion))
+
+			By("Check if Prometheus was reloaded only once")
+			Expect(reloads.Load()).To(Equal(int32(1)))
+
+			By("Check if a failed reload doesn't fail the reconciliation")
+			failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			DeferCleanup(failing.Close)
+			sloOperatorPrometheusReloadURL = failing.URL
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.SLOs[0].Objective = "99"
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reloads.Load()).To(Equal(int32(1)))
+
+			updated := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, updated)).To(Succeed())
+			Expect(updated.ResourceVersion).NotTo(Equal(configMap.ResourceVersion))
+		})
+	})
+})
+
+var _ = Describe("ServiceLevelObjective Controller (VMRule tenant)", func() {
+	Context("When reconciling a resource in a namespace with a tenant label", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-tenant",
+			Namespace: "tenant-a",
+		}
+
+		BeforeEach(func() {
+			sloOperatorMode = "victoriametrics"
+			sloOperatorTenantLabel = "example.com/tenant"
+			DeferCleanup(func() {
+				sloOperatorMode = ""
+				sloOperatorTenantLabel = ""
+			})
+
+			By("Creating the namespace with the tenant label")
+			namespace := &corev1.Namespace{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: "tenant-a"}, namespace)
+			if err != nil && errors.IsNotFound(err) {
+				Expect(k8sClient.Create(ctx, &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "tenant-a",
+						Labels: map[string]string{
+							"example.com/tenant": "42:0",
+						},
+					},
+				})).To(Succeed())
+			}
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			Expect(k8sClient.Create(ctx, &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tenant",
+					Namespace: "tenant-a",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+							RuleGroup: ricobergerdev1alpha1.RuleGroup{
+								VictoriaMetrics: ricobergerdev1alpha1.VictoriaMetricsRuleGroup{
+									Concurrency: 2,
+								},
+							},
+						},
+					},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("Should set the tenant and options of the VMRule groups", func() {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			vmRule := &vmv1beta1.VMRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, vmRule)).To(Succeed())
+			Expect(vmRule.Spec.Groups).To(HaveLen(3))
+			for _, group := range vmRule.Spec.Groups {
+				Expect(group.Tenant).To(Equal("42:0"))
+				Expect(group.Concurrency).To(Equal(2))
+			}
+		})
+	})
+})
+
+var _ = Describe("ServiceLevelObjective Controller (composite SLOs)", func() {
+	Context("When reconciling a resource with a composite SLO", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-composite",
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			Expect(k8sClient.Create(ctx, &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-composite",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+						{
+							Name:      "checkout",
+							Objective: "99",
+							Composite: &ricobergerdev1alpha1.Composite{
+								SLOs: []ricobergerdev1alpha1.CompositeSLO{
+									{Name: "availability"},
+									{Name: "availability", ServiceLevelObjective: "test-composite-payment"},
+								},
+							},
+						},
+					},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("Should fail until the referenced SLOs exist", func() {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+
+			By("Creating the referenced ServiceLevelObjective")
+			payment := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-composite-payment",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="payment"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="payment",code=~"5.."}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, payment)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, payment)).To(Succeed())
+			})
+
+			By("Requeuing the composite SLO for changes of the referenced ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			// The field index is only available in the cache of the manager, so
+			// that the ServiceLevelObjectives are looked up in a fake client.
+			indexedClient := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithIndex(&ricobergerdev1alpha1.ServiceLevelObjective{}, compositeServiceLevelObjectiveRefField, indexCompositeServiceLevelObjectiveRefs).
+				WithObjects(resource, payment.DeepCopy()).
+				Build()
+			indexedReconciler := &ServiceLevelObjectiveReconciler{Client: indexedClient}
+			Expect(indexedReconciler.findCompositeServiceLevelObjectivesForServiceLevelObjective(ctx, payment)).To(ConsistOf(reconcile.Request{NamespacedName: typeNamespacedName}))
+			Expect(indexedReconciler.findCompositeServiceLevelObjectivesForServiceLevelObjective(ctx, resource)).To(BeEmpty())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			prometheusRule := &monitoringv1.PrometheusRule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, prometheusRule)).To(Succeed())
+			Expect(prometheusRule.Spec.Groups).To(HaveLen(6))
+		})
+	})
+})
+
+var _ = Describe("generatePrometheusRuleGroup", func() {
+	labels := map[string]string{
+		"name":      "test",
+		"namespace": "default",
+	}
+
+	sli := ricobergerdev1alpha1.SLI{
+		TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+		ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+	}
+
+	It("Should apply the rule group options", func() {
+		groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99.9",
+			SLI:       sli,
+			RuleGroup: ricobergerdev1alpha1.RuleGroup{
+				Interval:                "1m",
+				QueryOffset:             "30s",
+				PartialResponseStrategy: "warn",
+				Labels:                  map[string]string{"tenant": "team-a"},
+				Limit:                   10,
+				VictoriaMetrics: ricobergerdev1alpha1.VictoriaMetricsRuleGroup{
+					EvalOffset:  "10s",
+					Concurrency: 2,
+					Params:      map[string][]string{"nocache": {"1"}},
+					Headers:     []string{"X-Custom: foo"},
+				},
+			},
+		}, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(2))
+
+		for _, group := range groups {
+			Expect(group.Interval).To(Equal(DurationPointer("1m")))
+			Expect(group.QueryOffset).To(Equal(DurationPointer("30s")))
+			Expect(group.PartialResponseStrategy).To(Equal("warn"))
+			Expect(group.Labels).To(Equal(map[string]string{"tenant": "team-a"}))
+			Expect(group.Limit).To(Equal(ptr.To(10)))
+
+			vmGroup := convertVMRuleGroup(group, "1:0", ricobergerdev1alpha1.VictoriaMetricsRuleGroup{
+				EvalOffset:  "10s",
+				Concurrency: 2,
+				Params:      map[string][]string{"nocache": {"1"}},
+				Headers:     []string{"X-Custom: foo"},
+			})
+			Expect(vmGroup.Name).To(Equal(group.Name))
+			Expect(vmGroup.Interval).To(Equal("1m"))
+			Expect(vmGroup.EvalDelay).To(Equal("30s"))
+			Expect(vmGroup.Labels).To(Equal(map[string]string{"tenant": "team-a"}))
+			Expect(vmGroup.Limit).To(Equal(10))
+			Expect(vmGroup.Tenant).To(Equal("1:0"))
+			Expect(vmGroup.EvalOffset).To(Equal("10s"))
+			Expect(vmGroup.Concurrency).To(Equal(2))
+			Expect(vmGroup.Params).To(HaveKeyWithValue("nocache", []string{"1"}))
+			Expect(vmGroup.Headers).To(Equal([]string{"X-Custom: foo"}))
+			Expect(vmGroup.Rules).To(HaveLen(len(group.Rules)))
+		}
+	})
+
+	It("Should render PromQL and MetricsQL expressions", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+		}
+
+		promQLGroups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		metricsQLGroups, err := generatePrometheusRuleGroup(slo, labels, metricsQL)
+		Expect(err).NotTo(HaveOccurred())
+
+		exprs := func(groups []monitoringv1.RuleGroup) map[string]string {
+			exprs := make(map[string]string)
+			for _, group := range groups {
+				for _, rule := range group.Rules {
+					if rule.Record != "" {
+						exprs[rule.Record+rule.Labels["window"]] = rule.Expr.String()
+					}
+				}
+			}
+			return exprs
+		}
+
+		By("Using the same rules and labels for both languages")
+		Expect(metricsQLGroups).To(HaveLen(len(promQLGroups)))
+		for i := range promQLGroups {
+			Expect(metricsQLGroups[i].Name).To(Equal(promQLGroups[i].Name))
+			Expect(metricsQLGroups[i].Rules).To(HaveLen(len(promQLGroups[i].Rules)))
+			for j := range promQLGroups[i].Rules {
+				Expect(metricsQLGroups[i].Rules[j].Record).To(Equal(promQLGroups[i].Rules[j].Record))
+				Expect(metricsQLGroups[i].Rules[j].Alert).To(Equal(promQLGroups[i].Rules[j].Alert))
+				Expect(metricsQLGroups[i].Rules[j].Labels).To(Equal(promQLGroups[i].Rules[j].Labels))
+			}
+		}
+
+		By("Rendering the PromQL expressions")
+		Expect(exprs(promQLGroups)).To(Equal(map[string]string{
+			"slo:window":       "2419200",
+			"slo:objective":    "0.99",
+			"slo:total":        `sum(rate(http_requests_total{job="api"}[2m]))`,
+			"slo:errors_total": `(sum(rate(http_requests_total{job="api",code=~"5.."}[2m]))) or vector(0)`,
+			"slo:availability": `1 - sum_over_time(slo:errors_total{id="test-default-availability"}[28d]) / sum_over_time(slo:total{id="test-default-availability"}[28d])`,
+			"slo:burnrate5m":   `(sum(rate(http_requests_total{job="api",code=~"5.."}[5m]))) / (sum(rate(http_requests_total{job="api"}[5m])))`,
+			"slo:burnrate30m":  `(sum(rate(http_requests_total{job="api",code=~"5.."}[30m]))) / (sum(rate(http_requests_total{job="api"}[30m])))`,
+			"slo:burnrate1h":   `(sum(rate(http_requests_total{job="api",code=~"5.."}[1h]))) / (sum(rate(http_requests_total{job="api"}[1h])))`,
+			"slo:burnrate2h":   `(sum(rate(http_requests_total{job="api",code=~"5.."}[2h]))) / (sum(rate(http_requests_total{job="api"}[2h])))`,
+			"slo:burnrate6h":   `(sum(rate(http_requests_total{job="api",code=~"5.."}[6h]))) / (sum(rate(http_requests_total{job="api"}[6h])))`,
+			"slo:burnrate1d":   `sum_over_time(slo:errors_total{id="test-default-availability"}[1d]) / sum_over_time(slo:total{id="test-default-availability"}[1d])`,
+			"slo:burnrate4d":   `sum_over_time(slo:errors_total{id="test-default-availability"}[4d]) / sum_over_time(slo:total{id="test-default-availability"}[4d])`,
+		}))
+
+		By("Rendering the MetricsQL expressions")
+		Expect(exprs(metricsQLGroups)).To(Equal(map[string]string{
+			"slo:window":       "2419200",
+			"slo:objective":    "0.99",
+			"slo:total":        `sum(rate(http_requests_total{job="api"}[2m]))`,
+			"slo:errors_total": `(sum(rate(http_requests_total{job="api",code=~"5.."}[2m]))) default 0`,
+			"slo:availability": `1 - sum_over_time(slo:errors_total{id="test-default-availability"}[28d]) / sum_over_time(slo:total{id="test-default-availability"}[28d])`,
+			"slo:burnrate5m":   `((sum(rate(http_requests_total{job="api",code=~"5.."}[5m]))) default 0) / (sum(rate(http_requests_total{job="api"}[5m])))`,
+			"slo:burnrate30m":  `((sum(rate(http_requests_total{job="api",code=~"5.."}[30m]))) default 0) / (sum(rate(http_requests_total{job="api"}[30m])))`,
+			"slo:burnrate1h":   `((sum(rate(http_requests_total{job="api",code=~"5.."}[1h]))) default 0) / (sum(rate(http_requests_total{job="api"}[1h])))`,
+			"slo:burnrate2h":   `((sum(rate(http_requests_total{job="api",code=~"5.."}[2h]))) default 0) / (sum(rate(http_requests_total{job="api"}[2h])))`,
+			"slo:burnrate6h":   `((sum(rate(http_requests_total{job="api",code=~"5.."}[6h]))) default 0) / (sum(rate(http_requests_total{job="api"}[6h])))`,
+			"slo:burnrate1d":   `sum_over_time(slo:errors_total{id="test-default-availability"}[1d]) / sum_over_time(slo:total{id="test-default-availability"}[1d])`,
+			"slo:burnrate4d":   `sum_over_time(slo:errors_total{id="test-default-availability"}[4d]) / sum_over_time(slo:total{id="test-default-availability"}[4d])`,
+		}))
+	})
+
+	It("Should render LogQL expressions", func() {
+		groups, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI: ricobergerdev1alpha1.SLI{
+				Type:       "LogQL",
+				TotalQuery: `sum(count_over_time({app="api"}[${window}]))`,
+				ErrorQuery: `sum(count_over_time({app="api"} |= "error" [${window}]))`,
+			},
+		}, labels, logQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(3))
+
+		rules := func(group monitoringv1.RuleGroup) []string {
+			var rules []string
+			for _, rule := range group.Rules {
+				if rule.Record != "" {
+					rules = append(rules, rule.Record+rule.Labels["window"]+": "+rule.Expr.String())
+				} else {
+					rules = append(rules, rule.Alert+": "+rule.Expr.String())
+				}
+			}
+			return rules
+		}
+
+		By("Evaluating only the short windows in Loki")
+		Expect(groups[0].Name).To(Equal("slo-generic-test-default-availability"))
+		Expect(rules(groups[0])).To(Equal([]string{
+			`slo:total: sum(count_over_time({app="api"}[2m]))`,
+			`slo:errors_total: (sum(count_over_time({app="api"} |= "error" [2m]))) or vector(0)`,
+		}))
+		Expect(groups[1].Name).To(Equal("slo-errors-test-default-availability"))
+		Expect(rules(groups[1])).To(Equal([]string{
+			`slo:burnrate5m: (sum(count_over_time({app="api"} |= "error" [5m]))) / (sum(count_over_time({app="api"}[5m])))`,
+			`slo:burnrate30m: (sum(count_over_time({app="api"} |= "error" [30m]))) / (sum(count_over_time({app="api"}[30m])))`,
+			`slo:burnrate1h: (sum(count_over_time({app="api"} |= "error" [1h]))) / (sum(count_over_time({app="api"}[1h])))`,
+			`slo:burnrate2h: (sum(count_over_time({app="api"} |= "error" [2h]))) / (sum(count_over_time({app="api"}[2h])))`,
+			`slo:burnrate6h: (sum(count_over_time({app="api"} |= "error" [6h]))) / (sum(count_over_time({app="api"}[6h])))`,
+		}))
+
+		By("Calculating the long windows and alerts from the recorded metrics")
+		Expect(groups[2].Name).To(Equal("slo-recorded-test-default-availability"))
+		Expect(rules(groups[2])).To(Equal([]string{
+			`slo:window: 2419200`,
+			`slo:objective: 0.99`,
+			`slo:availability: 1 - sum_over_time(slo:errors_total{id="test-default-availability"}[28d]) / sum_over_time(slo:total{id="test-default-availability"}[28d])`,
+			`slo:burnrate1d: sum_over_time(slo:errors_total{id="test-default-availability"}[1d]) / sum_over_time(slo:total{id="test-default-availability"}[1d])`,
+			`slo:burnrate4d: sum_over_time(slo:errors_total{id="test-default-availability"}[4d]) / sum_over_time(slo:total{id="test-default-availability"}[4d])`,
+			`SLOErrorBudgetBurn: slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.99))`,
+			`SLOErrorBudgetBurn: slo:burnrate{window="30m", id="test-default-availability"} > (7 * (1-0.99)) and ignoring(window) slo:burnrate{window="6h", id="test-default-availability"} > (7 * (1-0.99))`,
+			`SLOErrorBudgetBurn: slo:burnrate{window="2h", id="test-default-availability"} > (2 * (1-0.99)) and ignoring(window) slo:burnrate{window="1d", id="test-default-availability"} > (2 * (1-0.99))`,
+			`SLOErrorBudgetBurn: slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.99)) and ignoring(window) slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.99))`,
+		}))
+	})
+
+	It("Should protect the burn rate alerts for services with a low traffic", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Alerting: ricobergerdev1alpha1.Alerting{
+				MinimumEvents: 10,
+			},
+		}
+
+		By("Requiring a minimum number of events in the short window")
+		groups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[1].Rules[7].Alert).To(Equal("SLOErrorBudgetBurn"))
+		Expect(groups[1].Rules[7].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.99)) and ignoring(window) avg_over_time(slo:total{id="test-default-availability"}[5m]) * 300 > 10`))
+		Expect(groups[1].Rules[10].Expr.String()).To(Equal(`slo:burnrate{window="6h", id="test-default-availability"} > (1 * (1-0.99)) and ignoring(window) slo:burnrate{window="4d", id="test-default-availability"} > (1 * (1-0.99)) and ignoring(window) avg_over_time(slo:total{id="test-default-availability"}[6h]) * 21600 > 10`))
+
+		By("Using traffic-scaled thresholds")
+		slo.Alerting.MinimumEvents = 0
+		slo.Alerting.TrafficScaledThresholds = true
+		groups, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[1].Rules[7].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) clamp_min(1 / (avg_over_time(slo:total{id="test-default-availability"}[5m]) * 300), 14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) clamp_min(1 / (avg_over_time(slo:total{id="test-default-availability"}[1h]) * 3600), 14 * (1-0.99))`))
+
+	})
+
+	It("Should generate dynamic burn rate alerts", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Alerting: ricobergerdev1alpha1.Alerting{
+				BurnRateType: "Dynamic",
+			},
+		}
+
+		groups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[1].Rules).To(HaveLen(15))
+
+		By("Recording the traffic ratios")
+		Expect(groups[1].Rules[7]).To(Equal(monitoringv1.Rule{
+			Record: "slo:traffic_ratio",
+			Expr:   intstr.FromString(`avg_over_time(slo:total{id="test-default-availability"}[28d]) / avg_over_time(slo:total{id="test-default-availability"}[1h])`),
+			Labels: map[string]string{
+				"namespace": "default",
+				"name":      "test",
+				"id":        "test-default-availability",
+				"slo":       "availability",
+				"window":    "1h",
+			},
+		}))
+		Expect(groups[1].Rules[10].Expr.String()).To(Equal(`avg_over_time(slo:total{id="test-default-availability"}[28d]) / avg_over_time(slo:total{id="test-default-availability"}[4d])`))
+
+		By("Scaling the thresholds with the traffic ratio of the long window")
+		Expect(groups[1].Rules[11].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"} and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"}`))
+		Expect(groups[1].Rules[14].Expr.String()).To(Equal(`slo:burnrate{window="6h", id="test-default-availability"} > ignoring(window) (1 * (1-0.99)) * slo:traffic_ratio{window="4d", id="test-default-availability"} and ignoring(window) slo:burnrate{window="4d", id="test-default-availability"} > ignoring(window) (1 * (1-0.99)) * slo:traffic_ratio{window="4d", id="test-default-availability"}`))
+
+		By("Combining dynamic burn rates with traffic-scaled thresholds")
+		slo.Alerting.TrafficScaledThresholds = true
+		groups, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[1].Rules[11].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"} and ignoring(window) slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) 1 / (avg_over_time(slo:total{id="test-default-availability"}[5m]) * 300) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"} and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) 1 / (avg_over_time(slo:total{id="test-default-availability"}[1h]) * 3600)`))
+
+		By("Recording the traffic ratios for LogQL SLIs in the Prometheus group")
+		slo.SLI = ricobergerdev1alpha1.SLI{
+			Type:       "LogQL",
+			TotalQuery: `sum(rate({app="api"}[${window}]))`,
+			ErrorQuery: `sum(rate({app="api"} |= "error" [${window}]))`,
+		}
+		groups, err = generatePrometheusRuleGroup(slo, labels, logQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[2].Rules[5].Record).To(Equal("slo:traffic_ratio"))
+		Expect(groups[2].Rules[9].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"} and ignoring(window) slo:burnrate{window="5m", id="test-default-availability"} > ignoring(window) 1 / (avg_over_time(slo:total{id="test-default-availability"}[5m]) * 300) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) (14 * (1-0.99)) * slo:traffic_ratio{window="1h", id="test-default-availability"} and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > ignoring(window) 1 / (avg_over_time(slo:total{id="test-default-availability"}[1h]) * 3600)`))
+	})
+
+	It("Should generate the configured absent alert", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Alerting: ricobergerdev1alpha1.Alerting{
+				Absent: &ricobergerdev1alpha1.AbsentAlerting{
+					Selectors:  []string{`http_requests_total{job="api"}`, `http_requests_in_flight{job="api"}`},
+					ErrorQuery: true,
+					For:        "30m",
+					Severity:   "warning",
+				},
+			},
+		}
+
+		By("Checking the selectors and the error query")
+		groups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[0].Rules[5]).To(Equal(monitoringv1.Rule{
+			Alert: "SLOMetricAbsent",
+			Expr:  intstr.FromString(`absent(http_requests_total{job="api"}) == 1 or absent(http_requests_in_flight{job="api"}) == 1 or absent(sum(rate(http_requests_total{job="api",code=~"5.."}[2m]))) == 1`),
+			For:   DurationPointer("30m"),
+			Labels: map[string]string{
+				"namespace": "default",
+				"name":      "test",
+				"id":        "test-default-availability",
+				"slo":       "availability",
+				"severity":  "warning",
+			},
+		}))
+
+		By("Checking an own query")
+		slo.Alerting.Absent = &ricobergerdev1alpha1.AbsentAlerting{
+			Query: `sum(rate(http_requests_total{job="api"}[${window}])) by (instance)`,
+		}
+		groups, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[0].Rules[5].Expr.String()).To(Equal(`absent(sum(rate(http_requests_total{job="api"}[2m])) by (instance)) == 1`))
+		Expect(*groups[0].Rules[5].For).To(Equal(monitoringv1.Duration("10m")))
+		Expect(groups[0].Rules[5].Labels).To(HaveKeyWithValue("severity", "critical"))
+
+		By("Disabling the absent alert")
+		slo.Alerting.Absent = &ricobergerdev1alpha1.AbsentAlerting{Disabled: true}
+		groups, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[0].Rules).To(HaveLen(5))
+		Expect(groups[1].Rules).To(HaveLen(11))
+
+		By("Using an invalid duration")
+		slo.Alerting.Absent = &ricobergerdev1alpha1.AbsentAlerting{For: "10minutes"}
+		_, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should keep the group by labels", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			GroupBy:   []string{"route"},
+			SLI: ricobergerdev1alpha1.SLI{
+				TotalQuery: `sum by (route) (rate(http_requests_total{job="api"}[${window}]))`,
+				ErrorQuery: `sum by (route) (rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+			},
+		}
+
+		for _, language := range []queryLanguage{promQL, metricsQL} {
+			groups, err := generatePrometheusRuleGroup(slo, labels, language)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups[0].Rules[3].Record).To(Equal("slo:errors_total"))
+			Expect(groups[0].Rules[3].Expr.String()).To(Equal(`(sum by (route) (rate(http_requests_total{job="api",code=~"5.."}[2m]))) or on(route) (sum by (route) (rate(http_requests_total{job="api"}[2m]))) * 0`))
+		}
+
+		groups, err := generatePrometheusRuleGroup(slo, labels, metricsQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[1].Rules[0].Expr.String()).To(Equal(`((sum by (route) (rate(http_requests_total{job="api",code=~"5.."}[5m]))) or on(route) (sum by (route) (rate(http_requests_total{job="api"}[5m]))) * 0) / (sum by (route) (rate(http_requests_total{job="api"}[5m])))`))
+
+		By("Failing for queries, which don't aggregate by the labels")
+		slo.SLI.ErrorQuery = `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`
+		_, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should generate additional objectives", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Objectives: []ricobergerdev1alpha1.Objective{
+				{
+					Name:      "internal",
+					Objective: "99.95",
+					Alerting: ricobergerdev1alpha1.Alerting{
+						Severities: []string{"critical", "critical", "critical", "error", "error"},
+					},
+				},
+			},
+		}
+
+		groups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[0].Rules[6].Record).To(Equal("slo:objective"))
+		Expect(groups[0].Rules[6].Expr.String()).To(Equal("0.9995"))
+		Expect(groups[0].Rules[6].Labels).To(HaveKeyWithValue("objective", "internal"))
+
+		By("Reusing the burn rate recordings for the alerts of the objective")
+		Expect(groups[1].Rules).To(HaveLen(15))
+		Expect(groups[1].Rules[11].Alert).To(Equal("SLOErrorBudgetBurn"))
+		Expect(groups[1].Rules[11].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-availability"} > (14 * (1-0.9995)) and ignoring(window) slo:burnrate{window="1h", id="test-default-availability"} > (14 * (1-0.9995))`))
+		Expect(groups[1].Rules[11].Labels).To(HaveKeyWithValue("objective", "internal"))
+		Expect(groups[1].Rules[11].Labels).To(HaveKeyWithValue("severity", "critical"))
+
+		budgetGroups, err := generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(budgetGroups).To(HaveLen(2))
+		Expect(budgetGroups[0].Name).To(Equal("slo-budget-test-default-availability"))
+		Expect(budgetGroups[0].Rules[3].Expr.String()).To(Equal(`clamp_min(slo:error_budget_remaining{id="test-default-availability", objective=""}, 0) * 2419200 * (1-0.99) / ignoring(window) slo:burnrate{window="1h", id="test-default-availability"}`))
+		Expect(budgetGroups[1].Name).To(Equal("slo-budget-test-default-availability-internal"))
+		Expect(budgetGroups[1].Rules[0].Expr.String()).To(Equal(`(slo:availability{id="test-default-availability"} - 0.9995) / (1-0.9995)`))
+		Expect(budgetGroups[1].Rules[3].Expr.String()).To(Equal(`clamp_min(slo:error_budget_remaining{id="test-default-availability", objective="internal"}, 0) * 2419200 * (1-0.9995) / ignoring(window, objective) slo:burnrate{window="1h", id="test-default-availability"}`))
+
+		By("Failing for duplicated objectives")
+		slo.Objectives = append(slo.Objectives, slo.Objectives[0])
+		_, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should generate composite SLOs", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "checkout",
+			Objective: "99",
+			Composite: &ricobergerdev1alpha1.Composite{
+				SLOs: []ricobergerdev1alpha1.CompositeSLO{
+					{Name: "availability"},
+					{Name: "availability", ServiceLevelObjective: "payment", Namespace: "payment", Weight: "3"},
+				},
+			},
+		}
+
+		groups, err := generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Rules[2].Record).To(Equal("slo:availability"))
+		Expect(groups[0].Rules[2].Expr.String()).To(Equal(`(1 * min(slo:availability{id="test-default-availability"}) + 3 * min(slo:availability{id="payment-payment-availability"})) / 4`))
+		Expect(groups[0].Rules[3].Alert).To(Equal("SLOMetricAbsent"))
+		Expect(groups[0].Rules[3].Expr.String()).To(Equal(`absent(slo:availability{id="test-default-availability"}) == 1 or absent(slo:availability{id="payment-payment-availability"}) == 1`))
+		Expect(groups[1].Rules[0].Record).To(Equal("slo:burnrate"))
+		Expect(groups[1].Rules[0].Expr.String()).To(Equal(`(1 * max(slo:burnrate{window="5m", id="test-default-availability"}) + 3 * max(slo:burnrate{window="5m", id="payment-payment-availability"})) / 4`))
+		Expect(groups[1].Rules[7].Alert).To(Equal("SLOErrorBudgetBurn"))
+		Expect(groups[1].Rules[7].Expr.String()).To(Equal(`slo:burnrate{window="5m", id="test-default-checkout"} > (14 * (1-0.99)) and ignoring(window) slo:burnrate{window="1h", id="test-default-checkout"} > (14 * (1-0.99))`))
+
+		budgetGroups, err := generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).NotTo(HaveOccurred())
+		for _, rule := range budgetGroups[0].Rules {
+			Expect(rule.Record).NotTo(Equal("slo:error_budget_remaining_events"))
+		}
+
+		By("Multiplying the availabilities for the product type")
+		slo.Composite.Type = "Product"
+		groups, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups[0].Rules[2].Expr.String()).To(Equal(`min(slo:availability{id="test-default-availability"}) * min(slo:availability{id="payment-payment-availability"})`))
+		Expect(groups[1].Rules[0].Expr.String()).To(Equal(`1 - (1 - max(slo:burnrate{window="5m", id="test-default-availability"})) * (1 - max(slo:burnrate{window="5m", id="payment-payment-availability"}))`))
+
+		By("Failing for an invalid weight")
+		slo.Composite.SLOs[0].Weight = "0"
+		_, err = generatePrometheusRuleGroup(slo, labels, promQL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should generate the error budget threshold alerts", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Alerting: ricobergerdev1alpha1.Alerting{
+				ErrorBudgetThresholds: []ricobergerdev1alpha1.ErrorBudgetThreshold{
+					{Remaining: "50", Severity: "info"},
+					{Remaining: "25", Severity: "warning"},
+					{Remaining: "0", Severity: "critical"},
+				},
+			},
+		}
+
+		budgetGroups, err := generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(budgetGroups[0].Name).To(Equal("slo-budget-test-default-availability"))
+		Expect(budgetGroups[0].Rules).To(HaveLen(9))
+		Expect(budgetGroups[0].Rules[6:]).To(Equal([]monitoringv1.Rule{
+			{
+				Alert: "SLOErrorBudgetLow",
+				Expr:  intstr.FromString(`slo:error_budget_remaining{id="test-default-availability"} < 0.5`),
+				For:   DurationPointer("5m"),
+				Labels: map[string]string{
+					"namespace": "default",
+					"name":      "test",
+					"id":        "test-default-availability",
+					"slo":       "availability",
+					"severity":  "info",
+					"threshold": "50",
+				},
+			},
+			{
+				Alert: "SLOErrorBudgetLow",
+				Expr:  intstr.FromString(`slo:error_budget_remaining{id="test-default-availability"} < 0.25`),
+				For:   DurationPointer("5m"),
+				Labels: map[string]string{
+					"namespace": "default",
+					"name":      "test",
+					"id":        "test-default-availability",
+					"slo":       "availability",
+					"severity":  "warning",
+					"threshold": "25",
+				},
+			},
+			{
+				Alert: "SLOErrorBudgetExhausted",
+				Expr:  intstr.FromString(`slo:error_budget_remaining{id="test-default-availability"} <= 0`),
+				For:   DurationPointer("5m"),
+				Labels: map[string]string{
+					"namespace": "default",
+					"name":      "test",
+					"id":        "test-default-availability",
+					"slo":       "availability",
+					"severity":  "critical",
+					"threshold": "0",
+				},
+			},
+		}))
+
+		By("Disabling the alerting")
+		slo.Alerting.Disabled = true
+		budgetGroups, err = generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(budgetGroups[0].Rules).To(HaveLen(6))
+
+		By("Using an invalid threshold")
+		slo.Alerting.Disabled = false
+		slo.Alerting.ErrorBudgetThresholds = []ricobergerdev1alpha1.ErrorBudgetThreshold{{Remaining: "150", Severity: "info"}}
+		_, err = generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should generate the error budget forecast alert", func() {
+		slo := ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI:       sli,
+			Alerting: ricobergerdev1alpha1.Alerting{
+				Forecast: &ricobergerdev1alpha1.Forecast{
+					Horizon: "3d",
+				},
+			},
+		}
+
+		budgetGroups, err := generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(budgetGroups[0].Rules).To(HaveLen(7))
+		Expect(budgetGroups[0].Rules[4].Expr.String()).To(Equal(`predict_linear(slo:error_budget_remaining{id="test-default-availability"}[1d], 259200)`))
+		Expect(budgetGroups[0].Rules[6]).To(Equal(monitoringv1.Rule{
+			Alert: "SLOErrorBudgetForecast",
+			Expr:  intstr.FromString(`slo:error_budget_forecast{id="test-default-availability"} < 0`),
+			For:   DurationPointer("1h"),
+			Labels: map[string]string{
+				"namespace": "default",
+				"name":      "test",
+				"id":        "test-default-availability",
+				"slo":       "availability",
+				"severity":  "warning",
+			},
+		}))
+
+		By("Using an invalid horizon")
+		slo.Alerting.Forecast.Horizon = "3days"
+		_, err = generateErrorBudgetRuleGroups(slo, labels)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should fail for an invalid rule group interval", func() {
+		_, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99.9",
+			SLI:       sli,
+			RuleGroup: ricobergerdev1alpha1.RuleGroup{
+				Interval: "1 minute",
+			},
+		}, labels, promQL)
+		Expect(err).To(HaveOccurred())
 	})
 })
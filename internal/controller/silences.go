@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// errSilenceNotFound is returned by the alertmanagerClient, when a silence
+// which should be updated doesn't exist anymore.
+var errSilenceNotFound = errors.New("silence not found")
+
+// alertmanagerClient is a client for the v2 API of Alertmanager. The client
+// can be used to create, update and expire silences.
+//
+// See https://github.com/prometheus/alertmanager/blob/main/api/v2/openapi.yaml
+type alertmanagerClient struct {
+	// address is the address of Alertmanager, e.g. "http://alertmanager:9093".
+	address string
+}
+
+// alertmanagerSilence is a silence of the Alertmanager v2 API.
+type alertmanagerSilence struct {
+	ID        string                       `json:"id,omitempty"`
+	Matchers  []alertmanagerSilenceMatcher `json:"matchers"`
+	StartsAt  time.Time                    `json:"startsAt"`
+	EndsAt    time.Time                    `json:"endsAt"`
+	CreatedBy string                       `json:"createdBy"`
+	Comment   string                       `json:"comment"`
+}
+
+type alertmanagerSilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// setSilence creates the provided silence or updates it, when the id of the
+// silence is set. It returns the id of the silence, which can be different
+// from the provided id, because Alertmanager creates a new silence when the
+// matchers of an active silence are changed.
+func (c *alertmanagerClient) setSilence(ctx context.Context, silence alertmanagerSilence) (string, error) {
+	body, err := json.Marshal(silence)
+	if err != nil {
+		return "", err
+	}
+
+	respBody, err := c.do(ctx, http.MethodPost, "/api/v2/silences", body)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", err
+	}
+
+	return resp.SilenceID, nil
+}
+
+// expireSilence expires the silence with the provided id. If the silence
+// doesn't exist, no error is returned.
+func (c *alertmanagerClient) expireSilence(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/v2/silence/%s", url.PathEscape(id)), nil)
+	if errors.Is(err, errSilenceNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (c *alertmanagerClient) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", strings.TrimSuffix(c.address, "/"), path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errSilenceNotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d for %s %s: %s", resp.StatusCode, method, path, strings.TrimSpace(string(msg)))
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// reconcileSilences creates an Alertmanager silence for each of the provided
+// maintenance window occurrences, which matches the alerts of all SLOs of the
+// ServiceLevelObjective via the "id" label. Silences of occurrences, which are
+// not provided anymore, are expired. Silences which are over are removed from
+// the status, because they are expired by Alertmanager.
+//
+// The silences are tracked in the status of the ServiceLevelObjective, so that
+// existing silences are updated instead of creating new ones.
+func reconcileSilences(ctx context.Context, slo *ricobergerdev1alpha1.ServiceLevelObjective, ranges []timeRange) error {
+	if sloOperatorAlertmanagerURL == "" {
+		if len(ranges) > 0 || len(slo.Status.Silences) > 0 {
+			return fmt.Errorf("maintenance windows in the silences mode require the SLO_OPERATOR_ALERTMANAGER_URL environment variable")
+		}
+		return nil
+	}
+
+	client := &alertmanagerClient{address: sloOperatorAlertmanagerURL}
+	now := timeNow()
+
+	labels := map[string]string{"name": slo.Name, "namespace": slo.Namespace}
+	ids := make([]string, 0, len(slo.Spec.SLOs))
+	for _, s := range slo.Spec.SLOs {
+		ids = append(ids, regexp.QuoteMeta(generateSLOID(labels, s.Name)))
+	}
+	matchers := []alertmanagerSilenceMatcher{{Name: "id", Value: strings.Join(ids, "|"), IsRegex: true, IsEqual: true}}
+
+	existing := slo.Status.Silences
+	var statuses []ricobergerdev1alpha1.SilenceStatus
+
+	for _, r := range ranges {
+		// The ranges also contain maintenance windows, which ended within the
+		// maintenanceAlertDelay, but a silence is only needed until the end.
+		if !r.End.After(now) {
+			continue
+		}
+
+		silence := alertmanagerSilence{
+			Matchers:  matchers,
+			StartsAt:  r.Start,
+			EndsAt:    r.End,
+			CreatedBy: "slo-operator",
+			Comment:   fmt.Sprintf("Maintenance window %s of ServiceLevelObjective %s/%s", r.Name, slo.Namespace, slo.Name),
+		}
+
+		for i, status := range existing {
+			if status.MaintenanceWindow == r.Name && status.StartsAt.Equal(&metav1.Time{Time: r.Start}) && status.EndsAt.Equal(&metav1.Time{Time: r.End}) {
+				silence.ID = status.ID
+				existing = append(existing[:i:i], existing[i+1:]...)
+				break
+			}
+		}
+
+		id, err := client.setSilence(ctx, silence)
+		if errors.Is(err, errSilenceNotFound) {
+			silence.ID = ""
+			id, err = client.setSilence(ctx, silence)
+		}
+		if err != nil {
+			slo.Status.Silences = append(statuses, existing...)
+			return err
+		}
+
+		state := "active"
+		if r.Start.After(now) {
+			state = "pending"
+		}
+
+		statuses = append(statuses, ricobergerdev1alpha1.SilenceStatus{
+			ID:                id,
+			MaintenanceWindow: r.Name,
+			StartsAt:          metav1.Time{Time: r.Start},
+			EndsAt:            metav1.Time{Time: r.End},
+			State:             state,
+		})
+	}
+
+	// All remaining silences are not needed anymore. If they are not over yet,
+	// they must be expired, otherwise Alertmanager already expired them.
+	for i, status := range existing {
+		if status.EndsAt.After(now) {
+			if err := client.expireSilence(ctx, status.ID); err != nil {
+				slo.Status.Silences = append(statuses, existing[i:]...)
+				return err
+			}
+		}
+	}
+
+	slo.Status.Silences = statuses
+	return nil
+}
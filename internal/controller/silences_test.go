@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fakeAlertmanager is a local stand-in for the silences endpoints of the
+// Alertmanager v2 API, which stores all silences in memory.
+type fakeAlertmanager struct {
+	mu       sync.Mutex
+	nextID   int
+	silences map[string]alertmanagerSilence
+	// expired contains the ids of all expired silences.
+	expired map[string]bool
+}
+
+func newFakeAlertmanager() (*fakeAlertmanager, *httptest.Server) {
+	alertmanager := &fakeAlertmanager{
+		silences: make(map[string]alertmanagerSilence),
+		expired:  make(map[string]bool),
+	}
+	return alertmanager, httptest.NewServer(alertmanager)
+}
+
+func (f *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/silences":
+		var silence alertmanagerSilence
+		if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if silence.ID != "" {
+			if _, ok := f.silences[silence.ID]; !ok || f.expired[silence.ID] {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+		} else {
+			f.nextID++
+			silence.ID = fmt.Sprintf("silence-%d", f.nextID)
+		}
+		f.silences[silence.ID] = silence
+		_ = json.NewEncoder(w).Encode(map[string]string{"silenceID": silence.ID})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/silence/")
+		if _, ok := f.silences[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.expired[id] = true
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// activeSilences returns all silences, which are not expired.
+func (f *fakeAlertmanager) activeSilences() map[string]alertmanagerSilence {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	silences := make(map[string]alertmanagerSilence)
+	for id, silence := range f.silences {
+		if !f.expired[id] {
+			silences[id] = silence
+		}
+	}
+	return silences
+}
+
+var _ = Describe("ServiceLevelObjective Controller (Silences)", func() {
+	Context("When reconciling a resource", func() {
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      "test-silences",
+			Namespace: "default",
+		}
+
+		now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+		var alertmanager *fakeAlertmanager
+
+		BeforeEach(func() {
+			var server *httptest.Server
+			alertmanager, server = newFakeAlertmanager()
+			DeferCleanup(server.Close)
+
+			sloOperatorMaintenanceMode = "silences"
+			sloOperatorAlertmanagerURL = server.URL
+			timeNow = func() time.Time {
+				return now
+			}
+			DeferCleanup(func() {
+				sloOperatorMaintenanceMode = ""
+				sloOperatorAlertmanagerURL = ""
+				timeNow = time.Now
+			})
+
+			By("Creating the custom resource for the Kind ServiceLevelObjective")
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-silences",
+					Namespace: "default",
+				},
+				Spec: ricobergerdev1alpha1.ServiceLevelObjectiveSpec{
+					MaintenanceWindows: []ricobergerdev1alpha1.MaintenanceWindow{
+						{
+							Name:  "upgrade",
+							Start: &metav1.Time{Time: now.Add(1 * time.Hour)},
+							End:   &metav1.Time{Time: now.Add(2 * time.Hour)},
+						},
+						{
+							Name:  "migration",
+							Start: &metav1.Time{Time: now.Add(-1 * time.Hour)},
+							End:   &metav1.Time{Time: now.Add(1 * time.Hour)},
+						},
+					},
+					SLOs: []ricobergerdev1alpha1.SLO{
+						{
+							Name:      "availability",
+							Objective: "99",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_requests_total{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_requests_total{job="api",code=~"5.."}[${window}]))`,
+							},
+						},
+						{
+							Name:      "latency",
+							Objective: "95",
+							SLI: ricobergerdev1alpha1.SLI{
+								TotalQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}]))`,
+								ErrorQuery: `sum(rate(http_request_duration_seconds_count{job="api"}[${window}])) - sum(rate(http_request_duration_seconds_bucket{job="api",le="0.5"}[${window}]))`,
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		reconcileResource := func() error {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			return err
+		}
+
+		It("Should create, update and expire the silences", func() {
+			By("Reconciling the created resource")
+			Expect(reconcileResource()).To(Succeed())
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(resource, sloOperatorFinalizer)).To(BeTrue())
+			Expect(resource.Status.Silences).To(HaveLen(2))
+			Expect(resource.Status.Silences[0].ID).To(Equal("silence-1"))
+			Expect(resource.Status.Silences[0].MaintenanceWindow).To(Equal("upgrade"))
+			Expect(resource.Status.Silences[0].State).To(Equal("pending"))
+			Expect(resource.Status.Silences[1].ID).To(Equal("silence-2"))
+			Expect(resource.Status.Silences[1].MaintenanceWindow).To(Equal("migration"))
+			Expect(resource.Status.Silences[1].State).To(Equal("active"))
+
+			silences := alertmanager.activeSilences()
+			Expect(silences).To(HaveLen(2))
+			Expect(silences["silence-1"].StartsAt.Equal(now.Add(1 * time.Hour))).To(BeTrue())
+			Expect(silences["silence-1"].EndsAt.Equal(now.Add(2 * time.Hour))).To(BeTrue())
+			Expect(silences["silence-1"].CreatedBy).To(Equal("slo-operator"))
+			Expect(silences["silence-1"].Matchers).To(Equal([]alertmanagerSilenceMatcher{{
+				Name:    "id",
+				Value:   "test-silences-default-availability|test-silences-default-latency",
+				IsRegex: true,
+				IsEqual: true,
+			}}))
+
+			By("Reconciling the resource again, updates the existing silences")
+			Expect(reconcileResource()).To(Succeed())
+			Expect(alertmanager.activeSilences()).To(HaveLen(2))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Silences[0].ID).To(Equal("silence-1"))
+			Expect(resource.Status.Silences[1].ID).To(Equal("silence-2"))
+
+			By("Changing a maintenance window")
+			resource.Spec.MaintenanceWindows = resource.Spec.MaintenanceWindows[:1]
+			resource.Spec.MaintenanceWindows[0].End = &metav1.Time{Time: now.Add(3 * time.Hour)}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			silences = alertmanager.activeSilences()
+			Expect(silences).To(HaveLen(1))
+			Expect(silences).To(HaveKey("silence-3"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Silences).To(HaveLen(1))
+			Expect(resource.Status.Silences[0].ID).To(Equal("silence-3"))
+
+			By("Deleting the resource")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(reconcileResource()).To(Succeed())
+
+			Expect(alertmanager.activeSilences()).To(BeEmpty())
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Should requeue the resource at the next start or end of a maintenance window", func() {
+			controllerReconciler := &ServiceLevelObjectiveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("Reconciling the created resource")
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1*time.Hour + time.Second))
+
+			By("Reconciling the resource after the start of the pending window")
+			timeNow = func() time.Time {
+				return now.Add(1*time.Hour + time.Second)
+			}
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(1 * time.Hour))
+
+			resource := &ricobergerdev1alpha1.ServiceLevelObjective{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Silences).To(HaveLen(1))
+			Expect(resource.Status.Silences[0].MaintenanceWindow).To(Equal("upgrade"))
+			Expect(resource.Status.Silences[0].State).To(Equal("active"))
+
+			By("Reconciling the resource after the end of all windows")
+			timeNow = func() time.Time {
+				return now.Add(2*time.Hour + time.Second)
+			}
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Silences).To(BeEmpty())
+
+			By("Deleting the resource")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
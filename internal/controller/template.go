@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+)
+
+var (
+	// templateVarRegexp matches all placeholders in a query, which are written
+	// as "${name}", where the name must be a valid variable name. A
+	// placeholder can be escaped via "$${name}". Other expressions like
+	// "${1}" in the replacement of "label_replace" are not matched.
+	templateVarRegexp = regexp.MustCompile(`\$?\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+	// templateVarNameRegexp is the regular expression a variable name must
+	// match.
+	templateVarNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// renderQuery replaces each "${name}" placeholder in the provided query with
+// the value of the variable. Only the provided variables are available, so
+// that an error is returned when the query uses an undefined variable. An
+// escaped placeholder "$${name}" is rendered as "${name}". The query is
+// rendered in a single pass, which means that placeholders within the values
+// of the variables are kept as they are.
+func renderQuery(query string, vars map[string]string) (string, error) {
+	for name := range vars {
+		if !templateVarNameRegexp.MatchString(name) {
+			return "", fmt.Errorf("invalid variable name %s", name)
+		}
+	}
+
+	var err error
+	rendered := templateVarRegexp.ReplaceAllStringFunc(query, func(placeholder string) string {
+		if strings.HasPrefix(placeholder, "$$") {
+			return placeholder[1:]
+		}
+
+		name := templateVarRegexp.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("undefined variable %s", name)
+			}
+			return placeholder
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rendered, nil
+}
+
+// generateTemplateVars returns the variables, which can be used in the
+// queries of a SLO. These are the labels of the ServiceLevelObjective
+// (including the "name" and "namespace"), the name of the SLO as "slo" and
+// the user provided variables of the SLO. Labels, which are not a valid
+// variable name, are skipped.
+//
+// The "window" variable is rendered as "${window}" again, because it is
+// replaced with the different windows when the rules are generated.
+func generateTemplateVars(slo ricobergerdev1alpha1.SLO, labels map[string]string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for name, value := range labels {
+		if templateVarNameRegexp.MatchString(name) {
+			vars[name] = value
+		}
+	}
+	vars["slo"] = slo.Name
+
+	if _, ok := slo.Vars["window"]; ok {
+		return nil, fmt.Errorf("variable name window is reserved")
+	}
+	maps.Copy(vars, slo.Vars)
+	vars["window"] = "${window}"
+
+	return vars, nil
+}
+
+// renderSLOQueries renders the queries of the SLI and the absent alert of the
+// provided SLO with the variables of the SLO and returns the rendered SLO.
+//
+// The queries of a SLI, which references a ServiceLevelIndicator or a preset,
+// are already rendered with the parameters in resolveServiceLevelIndicators
+// and are not rendered again, so that each query is rendered exactly once.
+func renderSLOQueries(slo ricobergerdev1alpha1.SLO, labels map[string]string) (ricobergerdev1alpha1.SLO, error) {
+	vars, err := generateTemplateVars(slo, labels)
+	if err != nil {
+		return slo, err
+	}
+
+	if slo.SLI.Ref == nil && slo.SLI.Preset == nil {
+		if slo.SLI.TotalQuery, err = renderQuery(slo.SLI.TotalQuery, vars); err != nil {
+			return slo, fmt.Errorf("total query: %w", err)
+		}
+		if slo.SLI.ErrorQuery, err = renderQuery(slo.SLI.ErrorQuery, vars); err != nil {
+			return slo, fmt.Errorf("error query: %w", err)
+		}
+	}
+
+	if slo.Alerting.Absent != nil {
+		absent := slo.Alerting.Absent.DeepCopy()
+		if absent.Query, err = renderQuery(absent.Query, vars); err != nil {
+			return slo, fmt.Errorf("absent query: %w", err)
+		}
+		for i := range absent.Selectors {
+			if absent.Selectors[i], err = renderQuery(absent.Selectors[i], vars); err != nil {
+				return slo, fmt.Errorf("absent selector: %w", err)
+			}
+		}
+		slo.Alerting.Absent = absent
+	}
+
+	return slo, nil
+}
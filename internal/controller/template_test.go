@@ -0,0 +1,128 @@
+package controller
+
+import (
+	ricobergerdev1alpha1 "github.com/ricoberger/slo-operator/api/v1alpha1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("renderQuery", func() {
+	It("Should render the variables", func() {
+		query, err := renderQuery(`sum(rate(http_requests_total{job="${service}",namespace="${namespace}"}[${window}]))`, map[string]string{
+			"service":   "api",
+			"namespace": "default",
+			"window":    "${window}",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`sum(rate(http_requests_total{job="api",namespace="default"}[${window}]))`))
+	})
+
+	It("Should fail for undefined variables", func() {
+		_, err := renderQuery(`sum(rate(http_requests_total{job="${service}"}[5m]))`, nil)
+		Expect(err).To(HaveOccurred())
+
+		_, err = renderQuery(`sum(rate(http_requests_total{job="${service}"}[5m]))`, map[string]string{"namespace": "default"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should keep placeholders, which are not a variable", func() {
+		query, err := renderQuery(`label_replace(up{job="${job}"}, "dst", "${1}", "src", "(.*)")`, map[string]string{"job": "api"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`label_replace(up{job="api"}, "dst", "${1}", "src", "(.*)")`))
+	})
+
+	It("Should render escaped placeholders literally", func() {
+		query, err := renderQuery(`label_replace(up{job="${job}"}, "dst", "$${src}", "src", "(?P<src>.*)")`, map[string]string{"job": "api"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`label_replace(up{job="api"}, "dst", "${src}", "src", "(?P<src>.*)")`))
+	})
+
+	It("Should not expose template functions", func() {
+		query, err := renderQuery(`sum(rate(http_requests_total{job="${printf "%s" "api"}"}[5m]))`, map[string]string{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`sum(rate(http_requests_total{job="${printf "%s" "api"}"}[5m]))`))
+
+		query, err = renderQuery(`sum(rate(http_requests_total{job="${len}"}[5m]))`, map[string]string{"len": "api"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`sum(rate(http_requests_total{job="api"}[5m]))`))
+	})
+
+	It("Should not render placeholders in the values of the variables", func() {
+		query, err := renderQuery(`sum(rate(http_requests_total{job="${service}"}[5m]))`, map[string]string{
+			"service":   "${namespace}",
+			"namespace": "default",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal(`sum(rate(http_requests_total{job="${namespace}"}[5m]))`))
+	})
+
+	It("Should fail for invalid variable names", func() {
+		_, err := renderQuery(`up`, map[string]string{"service-name": "api"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("renderSLOQueries", func() {
+	labels := map[string]string{
+		"name":      "test",
+		"namespace": "default",
+		"team":      "team-a",
+	}
+
+	It("Should render the queries with the variables of the SLO", func() {
+		slo, err := renderSLOQueries(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			Vars: map[string]string{
+				"selector": `job="api",team="${team}"`,
+			},
+			SLI: ricobergerdev1alpha1.SLI{
+				TotalQuery: `sum(rate(http_requests_total{${selector},namespace="${namespace}"}[${window}]))`,
+				ErrorQuery: `sum(rate(http_requests_total{${selector},namespace="${namespace}",code=~"5.."}[${window}]))`,
+			},
+			Alerting: ricobergerdev1alpha1.Alerting{
+				Absent: &ricobergerdev1alpha1.AbsentAlerting{
+					Selectors: []string{`http_requests_total{slo="${slo}"}`},
+				},
+			},
+		}, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(slo.SLI.TotalQuery).To(Equal(`sum(rate(http_requests_total{job="api",team="${team}",namespace="default"}[${window}]))`))
+		Expect(slo.SLI.ErrorQuery).To(Equal(`sum(rate(http_requests_total{job="api",team="${team}",namespace="default",code=~"5.."}[${window}]))`))
+		Expect(slo.Alerting.Absent.Selectors).To(Equal([]string{`http_requests_total{slo="availability"}`}))
+	})
+
+	It("Should not render the queries of a ServiceLevelIndicator again", func() {
+		slo, err := renderSLOQueries(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI: ricobergerdev1alpha1.SLI{
+				Ref:        &ricobergerdev1alpha1.ServiceLevelIndicatorRef{Name: "http"},
+				TotalQuery: `sum(rate(http_requests_total{path="/${team}"}[${window}]))`,
+				ErrorQuery: `sum(rate(http_requests_total{path="/${undefined}",code=~"5.."}[${window}]))`,
+			},
+		}, labels)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(slo.SLI.TotalQuery).To(Equal(`sum(rate(http_requests_total{path="/${team}"}[${window}]))`))
+		Expect(slo.SLI.ErrorQuery).To(Equal(`sum(rate(http_requests_total{path="/${undefined}",code=~"5.."}[${window}]))`))
+	})
+
+	It("Should fail for undefined and reserved variables", func() {
+		_, err := generatePrometheusRuleGroup(ricobergerdev1alpha1.SLO{
+			Name:      "availability",
+			Objective: "99",
+			SLI: ricobergerdev1alpha1.SLI{
+				TotalQuery: `sum(rate(http_requests_total{job="${service}"}[${window}]))`,
+				ErrorQuery: `sum(rate(http_requests_total{job="${service}",code=~"5.."}[${window}]))`,
+			},
+		}, labels, promQL)
+		Expect(err).To(HaveOccurred())
+
+		_, err = renderSLOQueries(ricobergerdev1alpha1.SLO{
+			Name: "availability",
+			Vars: map[string]string{"window": "5m"},
+		}, labels)
+		Expect(err).To(HaveOccurred())
+	})
+})